@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+)
+
+// Handle 是 DoAsync 返回的句柄，用于取消和等待后台运行的重试
+type Handle struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// DoAsync 在新的 goroutine 中执行带重试的函数，并立即返回用于取消和等待的句柄
+// 重试循环使用 WithContext 设置的上下文（默认为 context.Background()）派生出的可取消上下文
+func DoAsync(fn RetryableFunc, opts ...Option) *Handle {
+	options := resolveOptions(opts...)
+	ctx, cancel := context.WithCancel(options.context())
+
+	h := &Handle{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.done)
+		defer cancel()
+
+		h.err = runFunc(ctx, options, func(ctx context.Context, attempt int) error {
+			return fn()
+		})
+	}()
+
+	return h
+}
+
+// Cancel 取消后台运行的重试，正在进行的退避等待会立即结束
+// 正在执行的 fn 不会被中断，重试循环在其返回后以 ErrContextCanceled 结束。可以重复调用
+func (h *Handle) Cancel() {
+	h.cancel()
+}
+
+// Done 返回在重试结束后关闭的通道
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err 返回重试的最终结果，只在 Done 返回的通道关闭后有效，之前调用返回 nil
+func (h *Handle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoAsyncCancelDuringBackoff(t *testing.T) {
+	started := make(chan struct{}, 1)
+	h := DoAsync(func() error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		return errors.New("transient")
+	}, WithRetryAllErrors(), WithMaxAttempts(5), WithBackoff(ConstantBackoff(time.Hour)))
+
+	<-started
+	if err := h.Err(); err != nil {
+		t.Errorf("Err before Done = %v, want nil", err)
+	}
+	h.Cancel()
+
+	select {
+	case <-h.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done not closed after Cancel")
+	}
+	if err := h.Err(); !errors.Is(err, ErrContextCanceled) {
+		t.Errorf("err = %v, want ErrContextCanceled", err)
+	}
+	h.Cancel()
+}
+
+func TestDoAsyncSucceeds(t *testing.T) {
+	calls := 0
+	h := DoAsync(func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	}, WithRetryAllErrors(), WithBackoff(ConstantBackoff(time.Millisecond)))
+
+	<-h.Done()
+	if err := h.Err(); err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
@@ -3,6 +3,7 @@ package retry
 import (
 	"math"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -13,27 +14,68 @@ func ConstantBackoff(interval time.Duration) BackoffFunc {
 	}
 }
 
+// ConstantBackoffWithJitter 返回带抖动的固定间隔重试策略
+// 公式: interval * random(1-jitter, 1+jitter)，jitter 会被限制在 [0, 1] 范围内
+func ConstantBackoffWithJitter(interval time.Duration, jitter float64) BackoffFunc {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	rng := packageRand()
+
+	return func(attempt int) time.Duration {
+		factor := 1 - jitter + rng.Float64()*2*jitter
+		return time.Duration(float64(interval) * factor)
+	}
+}
+
 // ExponentialBackoff 返回指数退避的重试策略
 // 公式: interval * 2^attempt
 func ExponentialBackoff(interval time.Duration, maxInterval time.Duration) BackoffFunc {
+	return ExponentialBackoffBase(interval, maxInterval, 2)
+}
+
+// minExponentialMultiplier 是指数退避底数的最小值，保证间隔持续增长
+const minExponentialMultiplier = 1.01
+
+// ExponentialBackoffBase 返回指定底数的指数退避重试策略
+// 公式: interval * multiplier^attempt，multiplier 小于 1.01 时按 1.01 处理，保证间隔持续增长
+func ExponentialBackoffBase(interval time.Duration, maxInterval time.Duration, multiplier float64) BackoffFunc {
+	if multiplier < minExponentialMultiplier {
+		multiplier = minExponentialMultiplier
+	}
+
 	return func(attempt int) time.Duration {
-		backoff := interval * time.Duration(math.Pow(2, float64(attempt)))
-		if backoff > maxInterval {
-			backoff = maxInterval
+		// 先以 float64 计算并截断，再转换为 time.Duration，避免 attempt 较大时溢出
+		backoff := float64(interval) * math.Pow(multiplier, float64(attempt))
+		if backoff > float64(maxInterval) {
+			backoff = float64(maxInterval)
 		}
-		return backoff
+		return time.Duration(backoff)
 	}
 }
 
 // ExponentialBackoffWithJitter 返回带抖动的指数退避重试策略
 // 公式: random(interval * 2^attempt * (1-jitter), interval * 2^attempt)
 func ExponentialBackoffWithJitter(interval time.Duration, maxInterval time.Duration, jitter float64) BackoffFunc {
+	return ExponentialBackoffWithJitterRand(interval, maxInterval, jitter, nil)
+}
+
+// ExponentialBackoffWithJitterRand 与 ExponentialBackoffWithJitter 相同，但使用指定的随机数生成器
+// rng 为 nil 时使用包内共享的随机数生成器。*rand.Rand 不是并发安全的，
+// 传入的 rng 不应在多个 goroutine 之间共享
+func ExponentialBackoffWithJitterRand(interval time.Duration, maxInterval time.Duration, jitter float64, rng *rand.Rand) BackoffFunc {
 	if jitter < 0 {
 		jitter = 0
 	}
 	if jitter > 1 {
 		jitter = 1
 	}
+	if rng == nil {
+		rng = packageRand()
+	}
 
 	return func(attempt int) time.Duration {
 		backoff := float64(interval) * math.Pow(2, float64(attempt))
@@ -45,8 +87,35 @@ func ExponentialBackoffWithJitter(interval time.Duration, maxInterval time.Durat
 		max := backoff
 
 		// 在 min 和 max 之间生成随机值
-		backoff = min + rand.Float64()*(max-min)
+		backoff = min + rng.Float64()*(max-min)
+
+		return time.Duration(backoff)
+	}
+}
+
+// ExponentialBackoffWithJitterBand 返回抖动范围可控的指数退避重试策略，抖动可以高于基准值
+// 公式: min(random(base * lower, base * upper), maxInterval)，其中 base = min(interval * 2^attempt, maxInterval)
+// lower 小于 0 时按 0 处理，upper 小于 lower 时按 lower 处理。
+// 例如 (0.8, 1.2) 为对称抖动，(0.5, 1.0) 与 jitter 为 0.5 的 ExponentialBackoffWithJitter 相同
+func ExponentialBackoffWithJitterBand(interval time.Duration, maxInterval time.Duration, lower, upper float64) BackoffFunc {
+	if lower < 0 {
+		lower = 0
+	}
+	if upper < lower {
+		upper = lower
+	}
+	rng := packageRand()
 
+	return func(attempt int) time.Duration {
+		base := float64(interval) * math.Pow(2, float64(attempt))
+		if base > float64(maxInterval) {
+			base = float64(maxInterval)
+		}
+
+		backoff := base * (lower + rng.Float64()*(upper-lower))
+		if backoff > float64(maxInterval) {
+			backoff = float64(maxInterval)
+		}
 		return time.Duration(backoff)
 	}
 }
@@ -62,3 +131,331 @@ func LinearBackoff(interval time.Duration, maxInterval time.Duration) BackoffFun
 		return backoff
 	}
 }
+
+// LinearBackoffWithJitter 返回带抖动的线性增长重试策略
+// 公式: random(base * (1-jitter), base)，其中 base = min(interval * (attempt + 1), maxInterval)，
+// jitter 会被限制在 [0, 1] 范围内
+func LinearBackoffWithJitter(interval time.Duration, maxInterval time.Duration, jitter float64) BackoffFunc {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	rng := packageRand()
+
+	return func(attempt int) time.Duration {
+		// 以 float64 计算并截断，避免 attempt 较大时溢出
+		backoff := float64(interval) * float64(attempt+1)
+		if backoff > float64(maxInterval) {
+			backoff = float64(maxInterval)
+		}
+		return time.Duration(backoff * (1 - jitter*rng.Float64()))
+	}
+}
+
+// FibonacciBackoff 返回斐波那契增长的重试策略
+// 公式: interval * fib(attempt)，其中 fib(0) = fib(1) = 1
+func FibonacciBackoff(interval time.Duration, maxInterval time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		prev, curr := interval, interval
+		for i := 1; i < attempt; i++ {
+			// 超过上限后立即截断，避免溢出
+			if curr >= maxInterval || prev > maxInterval-curr {
+				return maxInterval
+			}
+			prev, curr = curr, prev+curr
+		}
+
+		if curr > maxInterval {
+			curr = maxInterval
+		}
+		return curr
+	}
+}
+
+// DecorrelatedJitterBackoff 返回去相关抖动的重试策略（参见 AWS "Exponential Backoff And Jitter"）
+// 公式: min(cap, random(base, prev * 3))，其中 prev 为上一次的间隔
+// 返回的函数在内部保存上一次的间隔，是有状态的，不能在多个 goroutine 之间并发复用；
+// attempt 为 0 时状态会被重置
+func DecorrelatedJitterBackoff(base, cap time.Duration) BackoffFunc {
+	prev := base
+	rng := packageRand()
+
+	return func(attempt int) time.Duration {
+		if attempt == 0 {
+			prev = base
+		}
+
+		upper := float64(prev) * 3
+		backoff := float64(base) + rng.Float64()*(upper-float64(base))
+		if backoff > float64(cap) {
+			backoff = float64(cap)
+		}
+
+		prev = time.Duration(backoff)
+		return prev
+	}
+}
+
+// PolynomialBackoff 返回多项式增长的重试策略
+// 公式: interval * (attempt + 1)^exponent，exponent 不大于 0 时按 1 处理
+func PolynomialBackoff(interval time.Duration, maxInterval time.Duration, exponent float64) BackoffFunc {
+	if exponent <= 0 {
+		exponent = 1
+	}
+
+	return func(attempt int) time.Duration {
+		backoff := float64(interval) * math.Pow(float64(attempt+1), exponent)
+		if backoff > float64(maxInterval) {
+			backoff = float64(maxInterval)
+		}
+		return time.Duration(backoff)
+	}
+}
+
+// WithJitterBackoff 为任意重试策略增加抖动
+// 公式: random(base(attempt) * (1-jitter), base(attempt))，jitter 为 0 时直接返回 base
+func WithJitterBackoff(base BackoffFunc, jitter float64) BackoffFunc {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter == 0 {
+		return base
+	}
+	rng := packageRand()
+
+	return func(attempt int) time.Duration {
+		backoff := float64(base(attempt))
+		return time.Duration(backoff * (1 - jitter*rng.Float64()))
+	}
+}
+
+// JitterMode 指数退避的抖动模式
+type JitterMode int
+
+const (
+	// JitterNone 不加抖动，等同于 ExponentialBackoff
+	JitterNone JitterMode = iota
+	// JitterFull 完全抖动: random(0, base)，等同于 jitter 为 1 的 ExponentialBackoffWithJitter
+	JitterFull
+	// JitterEqual 等量抖动: base/2 + random(0, base/2)，等同于 jitter 为 0.5 的 ExponentialBackoffWithJitter
+	JitterEqual
+	// JitterDecorrelated 去相关抖动，等同于 DecorrelatedJitterBackoff，返回的函数是有状态的
+	JitterDecorrelated
+)
+
+// ExponentialBackoffMode 返回指定抖动模式的指数退避重试策略
+// 其中 base = min(interval * 2^attempt, maxInterval)
+func ExponentialBackoffMode(interval time.Duration, maxInterval time.Duration, mode JitterMode) BackoffFunc {
+	switch mode {
+	case JitterFull:
+		return ExponentialBackoffWithJitter(interval, maxInterval, 1)
+	case JitterEqual:
+		return ExponentialBackoffWithJitter(interval, maxInterval, 0.5)
+	case JitterDecorrelated:
+		return DecorrelatedJitterBackoff(interval, maxInterval)
+	default:
+		return ExponentialBackoff(interval, maxInterval)
+	}
+}
+
+// CapBackoff 为任意重试策略设置上限
+// 公式: min(base(attempt), max)
+func CapBackoff(base BackoffFunc, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		backoff := base(attempt)
+		if backoff > max {
+			backoff = max
+		}
+		return backoff
+	}
+}
+
+// FloorBackoff 为任意重试策略设置下限
+// 公式: max(base(attempt), min)
+// 与 CapBackoff、WithJitterBackoff 组合时，建议抖动在内、上限居中、下限在外，
+// 即 FloorBackoff(CapBackoff(WithJitterBackoff(base, jitter), max), min)，
+// 这样最终的间隔一定落在 [min, max] 范围内
+func FloorBackoff(base BackoffFunc, min time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		backoff := base(attempt)
+		if backoff < min {
+			backoff = min
+		}
+		return backoff
+	}
+}
+
+// SwitchBackoff 按重试次数分段组合两个重试策略
+// attempt < threshold 时使用 before(attempt)；之后使用 after(attempt - threshold)，
+// 即 after 的重试次数从 0 重新开始，例如前 3 次固定 100ms、之后指数退避：
+// SwitchBackoff(3, ConstantBackoff(100*time.Millisecond), ExponentialBackoff(100*time.Millisecond, 5*time.Second))
+func SwitchBackoff(threshold int, before, after BackoffFunc) BackoffFunc {
+	return func(attempt int) time.Duration {
+		if attempt < threshold {
+			return before(attempt)
+		}
+		return after(attempt - threshold)
+	}
+}
+
+// WeightedStrategy 是 WeightedBackoff 中带权重的重试策略
+type WeightedStrategy struct {
+	// Backoff 重试策略
+	Backoff BackoffFunc
+	// Weight 被选中的权重，不大于 0 的策略不会被选中
+	Weight float64
+}
+
+// WeightedBackoff 每次计算间隔时按权重随机选择一个重试策略，可用于混沌测试或比较不同策略在负载下的表现
+// 只有一个策略时直接返回该策略；所有权重之和不大于 0 时等概率选择；strategies 为空时间隔始终为 0
+func WeightedBackoff(strategies []WeightedStrategy) BackoffFunc {
+	if len(strategies) == 1 {
+		return strategies[0].Backoff
+	}
+	if len(strategies) == 0 {
+		return ConstantBackoff(0)
+	}
+
+	strategies = append([]WeightedStrategy(nil), strategies...)
+	var total float64
+	for _, s := range strategies {
+		if s.Weight > 0 {
+			total += s.Weight
+		}
+	}
+	rng := packageRand()
+
+	return func(attempt int) time.Duration {
+		if total <= 0 {
+			return strategies[rng.Intn(len(strategies))].Backoff(attempt)
+		}
+
+		target := rng.Float64() * total
+		for _, s := range strategies {
+			if s.Weight <= 0 {
+				continue
+			}
+			if target < s.Weight {
+				return s.Backoff(attempt)
+			}
+			target -= s.Weight
+		}
+
+		// 浮点误差导致未选中时使用最后一个权重为正的策略
+		for i := len(strategies) - 1; ; i-- {
+			if strategies[i].Weight > 0 {
+				return strategies[i].Backoff(attempt)
+			}
+		}
+	}
+}
+
+// StatefulBackoff 依赖上一次间隔的重试策略
+// 上一次的间隔由重试循环保存并传入，实现本身可以是无状态的，从而可以在多个 goroutine 之间安全复用
+type StatefulBackoff interface {
+	// Next 返回第 attempt 次失败后的重试间隔，prev 为上一次的间隔，第一次重试时为 0
+	Next(attempt int, prev time.Duration) time.Duration
+}
+
+// decorrelatedJitter 是无共享状态的去相关抖动策略
+type decorrelatedJitter struct {
+	base time.Duration
+	cap  time.Duration
+}
+
+// NewDecorrelatedJitter 返回去相关抖动的有状态重试策略，与 WithStatefulBackoff 配合使用
+// 公式与 DecorrelatedJitterBackoff 相同，但上一次的间隔由每次执行单独保存，可以并发复用
+func NewDecorrelatedJitter(base, cap time.Duration) StatefulBackoff {
+	return decorrelatedJitter{base: base, cap: cap}
+}
+
+// Next 实现 StatefulBackoff 接口
+func (d decorrelatedJitter) Next(attempt int, prev time.Duration) time.Duration {
+	if prev < d.base {
+		prev = d.base
+	}
+
+	upper := float64(prev) * 3
+	backoff := float64(d.base) + packageRand().Float64()*(upper-float64(d.base))
+	if backoff > float64(d.cap) {
+		backoff = float64(d.cap)
+	}
+	return time.Duration(backoff)
+}
+
+// BackoffFeedback 是 StatefulBackoff 可选实现的接口
+// 实现该接口时，重试循环会在每次尝试之后反馈成功或失败
+type BackoffFeedback interface {
+	// RecordSuccess 记录一次成功
+	RecordSuccess()
+	// RecordFailure 记录一次失败
+	RecordFailure()
+}
+
+// adaptiveFactor 是自适应重试策略每次调整间隔的倍数
+const adaptiveFactor = 2
+
+// adaptiveBackoff 根据最近的执行结果调整间隔的重试策略
+type adaptiveBackoff struct {
+	mu      sync.Mutex
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+// AdaptiveBackoff 返回根据最近执行结果自适应调整间隔的重试策略，与 WithStatefulBackoff 配合使用
+// 每次失败后间隔乘以 2，每次成功后间隔除以 2，并限制在 [min, max] 范围内，初始间隔为 min。
+// 与 NewDecorrelatedJitter 不同，间隔在所有使用该策略的调用之间共享，以便感知依赖的整体状态
+func AdaptiveBackoff(min, max time.Duration) StatefulBackoff {
+	// 间隔为 0 时无法通过倍增恢复，因此 min 至少为 1ms
+	if min <= 0 {
+		min = time.Millisecond
+	}
+	if max < min {
+		max = min
+	}
+	return &adaptiveBackoff{
+		min:     min,
+		max:     max,
+		current: min,
+	}
+}
+
+// Next 实现 StatefulBackoff 接口
+func (a *adaptiveBackoff) Next(attempt int, prev time.Duration) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.current
+}
+
+// RecordSuccess 实现 BackoffFeedback 接口
+func (a *adaptiveBackoff) RecordSuccess() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.current /= adaptiveFactor
+	if a.current < a.min {
+		a.current = a.min
+	}
+}
+
+// RecordFailure 实现 BackoffFeedback 接口
+func (a *adaptiveBackoff) RecordFailure() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.current > a.max/adaptiveFactor {
+		a.current = a.max
+		return
+	}
+	a.current *= adaptiveFactor
+	if a.current < a.min {
+		a.current = a.min
+	}
+}
@@ -0,0 +1,482 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFibonacciBackoff(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	backoff := FibonacciBackoff(interval, time.Hour)
+
+	for attempt, mult := range []int{1, 1, 2, 3, 5} {
+		if got, want := backoff(attempt), time.Duration(mult)*interval; got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestFibonacciBackoffCapped(t *testing.T) {
+	backoff := FibonacciBackoff(time.Second, time.Minute)
+
+	for _, attempt := range []int{10, 100, 10000} {
+		if got := backoff(attempt); got != time.Minute {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, time.Minute)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	const (
+		base  = 10 * time.Millisecond
+		cap   = 10 * time.Second
+		runs  = 1000
+		steps = 6
+	)
+
+	var sums [steps]time.Duration
+	for r := 0; r < runs; r++ {
+		backoff := DecorrelatedJitterBackoff(base, cap)
+		for attempt := 0; attempt < steps; attempt++ {
+			d := backoff(attempt)
+			if d < base || d > cap {
+				t.Fatalf("attempt %d: %v outside [%v, %v]", attempt, d, base, cap)
+			}
+			sums[attempt] += d
+		}
+	}
+
+	for attempt := 1; attempt < steps; attempt++ {
+		if sums[attempt] <= sums[attempt-1] {
+			t.Errorf("mean delay did not grow at attempt %d: %v <= %v",
+				attempt, sums[attempt]/runs, sums[attempt-1]/runs)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoffCapped(t *testing.T) {
+	backoff := DecorrelatedJitterBackoff(time.Second, 2*time.Second)
+	for attempt := 0; attempt < 50; attempt++ {
+		if d := backoff(attempt); d > 2*time.Second {
+			t.Fatalf("attempt %d: %v exceeds cap", attempt, d)
+		}
+	}
+}
+
+func TestPolynomialBackoff(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	tests := []struct {
+		exponent float64
+		want     []time.Duration
+	}{
+		{1.0, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond}},
+		{1.5, []time.Duration{100 * time.Millisecond, 282842712, 519615242, 800 * time.Millisecond}},
+		{2.0, []time.Duration{100 * time.Millisecond, 400 * time.Millisecond, 900 * time.Millisecond, 1600 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		backoff := PolynomialBackoff(interval, time.Hour, tt.exponent)
+		for attempt, want := range tt.want {
+			if got := backoff(attempt); got != want {
+				t.Errorf("exponent %v, attempt %d: got %v, want %v", tt.exponent, attempt, got, want)
+			}
+		}
+	}
+}
+
+func TestPolynomialBackoffEdgeCases(t *testing.T) {
+	linear := PolynomialBackoff(time.Second, time.Hour, -2)
+	if got := linear(2); got != 3*time.Second {
+		t.Errorf("non-positive exponent: got %v, want %v", got, 3*time.Second)
+	}
+
+	capped := PolynomialBackoff(time.Second, 5*time.Second, 2)
+	if got := capped(10); got != 5*time.Second {
+		t.Errorf("capped: got %v, want %v", got, 5*time.Second)
+	}
+}
+
+func TestCapBackoff(t *testing.T) {
+	// 没有上限的指数增长函数
+	unbounded := func(attempt int) time.Duration {
+		return time.Millisecond << attempt
+	}
+	backoff := CapBackoff(unbounded, 50*time.Millisecond)
+
+	for attempt := 0; attempt < 30; attempt++ {
+		want := unbounded(attempt)
+		if want > 50*time.Millisecond {
+			want = 50 * time.Millisecond
+		}
+		if got := backoff(attempt); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+
+	jittered := CapBackoff(WithJitterBackoff(unbounded, 0.5), 50*time.Millisecond)
+	for attempt := 0; attempt < 30; attempt++ {
+		if got := jittered(attempt); got > 50*time.Millisecond {
+			t.Errorf("jittered attempt %d: %v exceeds cap", attempt, got)
+		}
+	}
+}
+
+func TestFloorBackoff(t *testing.T) {
+	zero := func(attempt int) time.Duration { return 0 }
+	if got := FloorBackoff(zero, 5*time.Millisecond)(0); got != 5*time.Millisecond {
+		t.Errorf("zero base: got %v, want 5ms", got)
+	}
+
+	exact := FloorBackoff(ConstantBackoff(5*time.Millisecond), 5*time.Millisecond)
+	if got := exact(3); got != 5*time.Millisecond {
+		t.Errorf("base equal to floor: got %v, want 5ms", got)
+	}
+
+	above := FloorBackoff(ConstantBackoff(time.Second), 5*time.Millisecond)
+	if got := above(3); got != time.Second {
+		t.Errorf("base above floor: got %v, want 1s", got)
+	}
+}
+
+func TestFloorCapJitterEnvelope(t *testing.T) {
+	const lo, hi = 10 * time.Millisecond, 100 * time.Millisecond
+	backoff := FloorBackoff(CapBackoff(WithJitterBackoff(ExponentialBackoff(time.Millisecond, time.Hour), 1), hi), lo)
+
+	for attempt := 0; attempt < 20; attempt++ {
+		if got := backoff(attempt); got < lo || got > hi {
+			t.Errorf("attempt %d: %v outside [%v, %v]", attempt, got, lo, hi)
+		}
+	}
+}
+
+func TestConstantBackoffWithJitter(t *testing.T) {
+	const (
+		interval = 100 * time.Millisecond
+		jitter   = 0.2
+		samples  = 10000
+	)
+	backoff := ConstantBackoffWithJitter(interval, jitter)
+	lo := time.Duration(float64(interval) * (1 - jitter))
+	hi := time.Duration(float64(interval) * (1 + jitter))
+
+	var sum time.Duration
+	for i := 0; i < samples; i++ {
+		d := backoff(i)
+		if d < lo || d > hi {
+			t.Fatalf("sample %d: %v outside [%v, %v]", i, d, lo, hi)
+		}
+		sum += d
+	}
+
+	// 均值的标准差约为 0.07ms，允许 2ms 的误差
+	mean := sum / samples
+	if diff := mean - interval; diff < -2*time.Millisecond || diff > 2*time.Millisecond {
+		t.Errorf("mean = %v, want about %v", mean, interval)
+	}
+}
+
+func TestConstantBackoffWithJitterClamped(t *testing.T) {
+	backoff := ConstantBackoffWithJitter(time.Second, 5)
+	for i := 0; i < 1000; i++ {
+		if d := backoff(i); d < 0 || d > 2*time.Second {
+			t.Fatalf("sample %d: %v outside [0, 2s]", i, d)
+		}
+	}
+}
+
+func TestExponentialBackoffLargeAttempt(t *testing.T) {
+	const maxInterval = 30 * time.Second
+	backoff := ExponentialBackoff(time.Second, maxInterval)
+
+	if got := backoff(60); got != maxInterval {
+		t.Errorf("attempt 60: got %v, want %v", got, maxInterval)
+	}
+	for attempt := 0; attempt < 2000; attempt++ {
+		if got := backoff(attempt); got < 0 || got > maxInterval {
+			t.Fatalf("attempt %d: %v outside [0, %v]", attempt, got, maxInterval)
+		}
+	}
+}
+
+func TestExponentialBackoffBase(t *testing.T) {
+	backoff := ExponentialBackoffBase(time.Second, time.Hour, 1.5)
+	want := []time.Duration{time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond, 3375 * time.Millisecond, 5062500 * time.Microsecond}
+
+	for attempt, w := range want {
+		if got := backoff(attempt); got != w {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, w)
+		}
+	}
+}
+
+func TestExponentialBackoffBaseMinimumMultiplier(t *testing.T) {
+	for _, multiplier := range []float64{1, 0.5, -2} {
+		backoff := ExponentialBackoffBase(time.Second, time.Hour, multiplier)
+		if backoff(10) <= backoff(9) {
+			t.Errorf("multiplier %v: backoff does not grow", multiplier)
+		}
+	}
+}
+
+// stepBackoff 每次在上一次的间隔上增加 step，用于检查每次执行是否单独保存上一次的间隔
+type stepBackoff struct {
+	step time.Duration
+}
+
+func (s stepBackoff) Next(attempt int, prev time.Duration) time.Duration {
+	return prev + s.step
+}
+
+func TestStatefulBackoffConcurrent(t *testing.T) {
+	const goroutines = 16
+	shared := stepBackoff{step: time.Microsecond}
+	jitter := NewDecorrelatedJitter(time.Microsecond, 50*time.Microsecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*5)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var delays []time.Duration
+			_ = Do(func() error { return errBench },
+				WithRetryAllErrors(),
+				WithMaxAttempts(5),
+				WithStatefulBackoff(shared),
+				WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+			)
+			for i, d := range delays {
+				if want := time.Duration(i+1) * time.Microsecond; d != want {
+					errs <- fmt.Errorf("delay %d = %v, want %v", i, d, want)
+					return
+				}
+			}
+
+			_ = Do(func() error { return errBench },
+				WithRetryAllErrors(),
+				WithMaxAttempts(5),
+				WithStatefulBackoff(jitter),
+				WithOnBackoff(func(attempt int, delay time.Duration) {
+					if delay < time.Microsecond || delay > 50*time.Microsecond {
+						errs <- fmt.Errorf("jitter delay %v outside [1µs, 50µs]", delay)
+					}
+				}),
+			)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestAdaptiveBackoffTrajectory(t *testing.T) {
+	b := AdaptiveBackoff(10*time.Millisecond, 100*time.Millisecond)
+	feedback := b.(BackoffFeedback)
+
+	steps := []struct {
+		success bool
+		want    time.Duration
+	}{
+		{false, 20 * time.Millisecond},
+		{false, 40 * time.Millisecond},
+		{false, 80 * time.Millisecond},
+		{false, 100 * time.Millisecond},
+		{false, 100 * time.Millisecond},
+		{true, 50 * time.Millisecond},
+		{true, 25 * time.Millisecond},
+		{true, 12500 * time.Microsecond},
+		{true, 10 * time.Millisecond},
+		{true, 10 * time.Millisecond},
+	}
+	if got := b.Next(0, 0); got != 10*time.Millisecond {
+		t.Fatalf("initial delay = %v, want 10ms", got)
+	}
+	for i, step := range steps {
+		if step.success {
+			feedback.RecordSuccess()
+		} else {
+			feedback.RecordFailure()
+		}
+		if got := b.Next(i, 0); got != step.want {
+			t.Errorf("step %d: got %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestAdaptiveBackoffFedByLoop(t *testing.T) {
+	b := AdaptiveBackoff(time.Millisecond, time.Second)
+	calls := 0
+	err := Do(func() error {
+		calls++
+		if calls <= 3 {
+			return errBench
+		}
+		return nil
+	}, WithRetryAllErrors(), WithMaxAttempts(5), WithStatefulBackoff(b))
+
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	// 3 次失败使间隔变为 8ms，之后的 1 次成功使其变为 4ms
+	if got := b.Next(0, 0); got != 4*time.Millisecond {
+		t.Errorf("delay after run = %v, want 4ms", got)
+	}
+}
+
+func TestSwitchBackoff(t *testing.T) {
+	before := ConstantBackoff(100 * time.Millisecond)
+	after := func(attempt int) time.Duration { return time.Duration(attempt+1) * time.Second }
+	backoff := SwitchBackoff(3, before, after)
+
+	// after 的重试次数从 0 重新开始
+	want := []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond, time.Second, 2 * time.Second}
+	for attempt, w := range want {
+		if got := backoff(attempt); got != w {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, w)
+		}
+	}
+}
+
+func TestSwitchBackoffZeroThreshold(t *testing.T) {
+	backoff := SwitchBackoff(0, ConstantBackoff(time.Hour), ExponentialBackoff(time.Second, time.Minute))
+	if got := backoff(0); got != time.Second {
+		t.Errorf("attempt 0: got %v, want 1s", got)
+	}
+}
+
+func TestExponentialBackoffWithJitterBand(t *testing.T) {
+	const interval, maxInterval = 100 * time.Millisecond, 2 * time.Second
+
+	tests := []struct {
+		name         string
+		lower, upper float64
+	}{
+		{"symmetric", 0.8, 1.2},
+		{"one-sided", 0.5, 1.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backoff := ExponentialBackoffWithJitterBand(interval, maxInterval, tt.lower, tt.upper)
+			var above bool
+			for i := 0; i < 2000; i++ {
+				attempt := i % 6
+				base := min(interval<<attempt, maxInterval)
+				lo := time.Duration(float64(base) * tt.lower)
+				hi := min(time.Duration(float64(base)*tt.upper), maxInterval)
+
+				d := backoff(attempt)
+				if d < lo || d > hi {
+					t.Fatalf("attempt %d: %v outside [%v, %v]", attempt, d, lo, hi)
+				}
+				above = above || d > base
+			}
+			if wantAbove := tt.upper > 1; above != wantAbove {
+				t.Errorf("delays above base = %v, want %v", above, wantAbove)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffWithJitterBandCapped(t *testing.T) {
+	backoff := ExponentialBackoffWithJitterBand(time.Second, 5*time.Second, 1, 3)
+	for i := 0; i < 100; i++ {
+		if d := backoff(10); d != 5*time.Second {
+			t.Fatalf("got %v, want the 5s cap", d)
+		}
+	}
+}
+
+func TestWeightedBackoffDistribution(t *testing.T) {
+	const samples = 20000
+	backoff := WeightedBackoff([]WeightedStrategy{
+		{Backoff: ConstantBackoff(1 * time.Millisecond), Weight: 1},
+		{Backoff: ConstantBackoff(2 * time.Millisecond), Weight: 3},
+		{Backoff: ConstantBackoff(3 * time.Millisecond), Weight: 0},
+	})
+
+	counts := map[time.Duration]int{}
+	for i := 0; i < samples; i++ {
+		counts[backoff(i)]++
+	}
+
+	if counts[3*time.Millisecond] != 0 {
+		t.Errorf("zero-weight strategy selected %d times", counts[3*time.Millisecond])
+	}
+	// 期望比例为 25%，标准差约为 0.3%，允许 2% 的误差
+	if share := float64(counts[time.Millisecond]) / samples; share < 0.23 || share > 0.27 {
+		t.Errorf("weight-1 strategy share = %.3f, want about 0.25", share)
+	}
+}
+
+func TestWeightedBackoffEdgeCases(t *testing.T) {
+	single := WeightedBackoff([]WeightedStrategy{{Backoff: ConstantBackoff(time.Second), Weight: 0}})
+	if got := single(0); got != time.Second {
+		t.Errorf("single strategy: got %v, want 1s", got)
+	}
+
+	if got := WeightedBackoff(nil)(0); got != 0 {
+		t.Errorf("no strategies: got %v, want 0", got)
+	}
+
+	uniform := WeightedBackoff([]WeightedStrategy{
+		{Backoff: ConstantBackoff(time.Millisecond)},
+		{Backoff: ConstantBackoff(2 * time.Millisecond)},
+	})
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 200; i++ {
+		seen[uniform(i)] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("non-positive total weight: selected %d strategies, want 2", len(seen))
+	}
+}
+
+func TestLinearBackoffWithJitter(t *testing.T) {
+	const (
+		interval    = 100 * time.Millisecond
+		maxInterval = 450 * time.Millisecond
+		jitter      = 0.4
+		samples     = 5000
+	)
+	backoff := LinearBackoffWithJitter(interval, maxInterval, jitter)
+	linear := LinearBackoff(interval, maxInterval)
+
+	for attempt := 0; attempt < 6; attempt++ {
+		base := linear(attempt)
+		lo := time.Duration(float64(base) * (1 - jitter))
+
+		var sum time.Duration
+		for i := 0; i < samples; i++ {
+			d := backoff(attempt)
+			if d < lo || d > base {
+				t.Fatalf("attempt %d: %v outside [%v, %v]", attempt, d, lo, base)
+			}
+			sum += d
+		}
+
+		// 抖动系数的期望为 1 - jitter/2
+		want := time.Duration(float64(base) * (1 - jitter/2))
+		mean := sum / samples
+		if diff := mean - want; diff < -want/50 || diff > want/50 {
+			t.Errorf("attempt %d: mean = %v, want about %v", attempt, mean, want)
+		}
+	}
+}
+
+func TestLinearBackoffWithJitterClamped(t *testing.T) {
+	if got := LinearBackoffWithJitter(time.Second, time.Minute, 0)(2); got != 3*time.Second {
+		t.Errorf("zero jitter: got %v, want 3s", got)
+	}
+	backoff := LinearBackoffWithJitter(time.Second, time.Minute, 2)
+	for i := 0; i < 100; i++ {
+		if d := backoff(0); d < 0 || d > time.Second {
+			t.Fatalf("jitter above 1: %v outside [0, 1s]", d)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"sync"
+)
+
+// DoBatch 使用相同的重试选项依次执行多个函数，返回与 fns 下标对应的最终错误（成功为 nil）
+func DoBatch(fns []RetryableFunc, opts ...Option) []error {
+	options := resolveOptions(opts...)
+
+	errs := make([]error, len(fns))
+	for i, fn := range fns {
+		errs[i] = runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+			return fn()
+		})
+	}
+
+	return errs
+}
+
+// DoBatchContext 使用相同的重试选项依次执行多个带上下文的函数
+// 上下文结束后不再执行剩余的函数，其对应的错误为上下文结束的哨兵错误
+func DoBatchContext(ctx context.Context, fns []RetryableFuncWithContext, opts ...Option) []error {
+	options := resolveOptions(opts...)
+
+	errs := make([]error, len(fns))
+	for i, fn := range fns {
+		if ctx.Err() != nil {
+			errs[i] = newContextError(ctx, nil, 0)
+			continue
+		}
+
+		errs[i] = run(ctx, options, func(ctx context.Context, attempt int) error {
+			return fn(ctx)
+		})
+	}
+
+	return errs
+}
+
+// DoConcurrent 并发执行多个带上下文的函数，每个函数拥有独立的重试循环
+// 同时执行的函数数量不超过 concurrency（不大于 0 时按 1 处理），返回与 fns 下标对应的最终错误。
+// 上下文结束后不再启动新的函数，未启动的函数对应的错误为上下文结束的哨兵错误。
+// 选项中的回调函数（如 OnRetry）会被并发调用，调用方需要保证其并发安全；
+// 有状态的重试策略（如 DecorrelatedJitterBackoff）也不应在此使用
+func DoConcurrent(ctx context.Context, fns []RetryableFuncWithContext, concurrency int, opts ...Option) []error {
+	options := resolveOptions(opts...)
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	errs := make([]error, len(fns))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, fn := range fns {
+		select {
+		case <-ctx.Done():
+			errs[i] = newContextError(ctx, nil, 0)
+			continue
+		case sem <- struct{}{}:
+		}
+
+		// 获取到执行名额时上下文可能已经结束
+		if ctx.Err() != nil {
+			<-sem
+			errs[i] = newContextError(ctx, nil, 0)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			errs[i] = run(ctx, options, func(ctx context.Context, attempt int) error {
+				return fn(ctx)
+			})
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// itemErrors 返回 n 个互不相同的错误，用于检查结果与下标的对应关系
+func itemErrors(n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = fmt.Errorf("item %d", i)
+	}
+	return errs
+}
+
+func TestDoBatchPreservesOrder(t *testing.T) {
+	want := itemErrors(4)
+	fns := make([]RetryableFunc, len(want))
+	for i := range fns {
+		fns[i] = func() error {
+			if i%2 == 0 {
+				return nil
+			}
+			return Permanent(want[i])
+		}
+	}
+
+	errs := DoBatch(fns, WithRetryAllErrors())
+
+	for i, err := range errs {
+		if i%2 == 0 && err != nil {
+			t.Errorf("errs[%d] = %v, want nil", i, err)
+		}
+		if i%2 == 1 && !errors.Is(err, want[i]) {
+			t.Errorf("errs[%d] = %v, want %v", i, err, want[i])
+		}
+	}
+}
+
+func TestDoBatchContextStopsAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var started atomic.Int32
+	fns := make([]RetryableFuncWithContext, 5)
+	for i := range fns {
+		fns[i] = func(ctx context.Context) error {
+			if started.Add(1) == 2 {
+				cancel()
+			}
+			return nil
+		}
+	}
+
+	errs := DoBatchContext(ctx, fns)
+
+	if n := started.Load(); n != 2 {
+		t.Errorf("started = %d, want 2", n)
+	}
+	for i, err := range errs {
+		if i < 2 && err != nil {
+			t.Errorf("errs[%d] = %v, want nil", i, err)
+		}
+		if i >= 2 && !errors.Is(err, ErrContextCanceled) {
+			t.Errorf("errs[%d] = %v, want ErrContextCanceled", i, err)
+		}
+	}
+}
+
+func TestDoConcurrentLimitsConcurrency(t *testing.T) {
+	const concurrency = 3
+	want := itemErrors(12)
+	var active, peak atomic.Int32
+	fns := make([]RetryableFuncWithContext, len(want))
+	for i := range fns {
+		fns[i] = func(ctx context.Context) error {
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			active.Add(-1)
+			if i%2 == 0 {
+				return nil
+			}
+			return Permanent(want[i])
+		}
+	}
+
+	errs := DoConcurrent(context.Background(), fns, concurrency, WithRetryAllErrors())
+
+	if p := peak.Load(); p > concurrency {
+		t.Errorf("peak concurrency = %d, want <= %d", p, concurrency)
+	}
+	for i, err := range errs {
+		if i%2 == 0 && err != nil {
+			t.Errorf("errs[%d] = %v, want nil", i, err)
+		}
+		if i%2 == 1 && !errors.Is(err, want[i]) {
+			t.Errorf("errs[%d] = %v, want %v", i, err, want[i])
+		}
+	}
+}
+
+func TestDoConcurrentCancelSkipsRemaining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var started atomic.Int32
+	fns := make([]RetryableFuncWithContext, 6)
+	for i := range fns {
+		fns[i] = func(ctx context.Context) error {
+			if started.Add(1) == 2 {
+				cancel()
+			}
+			return nil
+		}
+	}
+
+	errs := DoConcurrent(ctx, fns, 1)
+
+	if n := started.Load(); n != 2 {
+		t.Errorf("started = %d, want 2", n)
+	}
+	for i := 2; i < len(errs); i++ {
+		if !errors.Is(errs[i], ErrContextCanceled) {
+			t.Errorf("errs[%d] = %v, want ErrContextCanceled", i, errs[i])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBench = errors.New("bench")
+
+// BenchmarkDoSingleAttempt 衡量单次成功调用的开销。选项通过函数值应用，Options 会逃逸到堆上，
+// 因此仍有 1 次分配（约 448 B）
+func BenchmarkDoSingleAttempt(b *testing.B) {
+	opts := []Option{WithMaxAttempts(1)}
+	fn := func() error { return nil }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Do(fn, opts...)
+	}
+}
+
+// BenchmarkDoWithContextSingleAttempt 在 Options 之外还会为单次尝试派生上下文，共 2 次分配（约 496 B）
+func BenchmarkDoWithContextSingleAttempt(b *testing.B) {
+	ctx := context.Background()
+	opts := []Option{WithMaxAttempts(1)}
+	fn := func(ctx context.Context) error { return nil }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = DoWithContext(ctx, fn, opts...)
+	}
+}
+
+func BenchmarkDoZeroBackoff(b *testing.B) {
+	opts := []Option{WithMaxAttempts(3), WithRetryAllErrors(), WithBackoff(ConstantBackoff(0))}
+	fn := func() error { return errBench }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Do(fn, opts...)
+	}
+}
+
+// BenchmarkDoWithContextBackoff 衡量可取消上下文下多次等待的开销，同一次调用中的定时器会被复用
+func BenchmarkDoWithContextBackoff(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := []Option{WithMaxAttempts(5), WithRetryAllErrors(), WithBackoff(ConstantBackoff(time.Nanosecond))}
+	fn := func(ctx context.Context) error { return errBench }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = DoWithContext(ctx, fn, opts...)
+	}
+}
@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudget 跨调用共享的重试预算，用于防止故障期间的重试风暴
+type RetryBudget interface {
+	// Allow 在每次重试（不包括第一次尝试）之前调用，返回 false 表示预算已用完
+	Allow() bool
+	// Return 在每次执行开始时调用一次，按请求数向预算归还额度
+	Return()
+}
+
+// TokenRetryBudget 基于令牌桶的重试预算，可并发使用
+// 每次执行开始时存入 ratio 个令牌，每次重试消耗 1 个令牌，
+// 此外每秒额外允许 minPerSec 次重试，保证低流量时也能重试
+type TokenRetryBudget struct {
+	mu         sync.Mutex
+	ratio      float64
+	maxTokens  float64
+	tokens     float64
+	minPerSec  float64
+	reserve    float64
+	lastRefill time.Time
+}
+
+// NewRetryBudget 创建新的令牌桶重试预算
+// ratio 为允许重试的比例（例如 0.1 表示重试次数不超过请求数的 10%），
+// 累积的令牌最多相当于最近 1000 次请求的额度
+func NewRetryBudget(ratio float64, minPerSec int) *TokenRetryBudget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if minPerSec < 0 {
+		minPerSec = 0
+	}
+	return &TokenRetryBudget{
+		ratio:      ratio,
+		maxTokens:  ratio * 1000,
+		minPerSec:  float64(minPerSec),
+		reserve:    float64(minPerSec),
+		lastRefill: time.Now(),
+	}
+}
+
+// Allow 实现 RetryBudget 接口
+func (b *TokenRetryBudget) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.reserve += now.Sub(b.lastRefill).Seconds() * b.minPerSec
+	if b.reserve > b.minPerSec {
+		b.reserve = b.minPerSec
+	}
+	b.lastRefill = now
+
+	if b.reserve >= 1 {
+		b.reserve--
+		return true
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	return false
+}
+
+// Return 实现 RetryBudget 接口
+func (b *TokenRetryBudget) Return() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRetryBudgetSuppressesRetries(t *testing.T) {
+	budget := NewRetryBudget(0.5, 0)
+	opts := []Option{WithRetryAllErrors(), WithMaxAttempts(10), WithBackoff(ConstantBackoff(0)), WithRetryBudget(budget)}
+
+	// 每次执行存入 0.5 个令牌，每两次执行才能重试一次
+	for i, want := range []int{1, 2, 1, 2} {
+		calls := 0
+		err := Do(func() error {
+			calls++
+			return errors.New("transient")
+		}, opts...)
+
+		if !errors.Is(err, ErrRetryBudgetExhausted) {
+			t.Fatalf("run %d: err = %v, want ErrRetryBudgetExhausted", i, err)
+		}
+		if calls != want {
+			t.Errorf("run %d: calls = %d, want %d", i, calls, want)
+		}
+	}
+}
+
+func TestRetryBudgetNotUsedOnSuccess(t *testing.T) {
+	budget := NewRetryBudget(1, 0)
+	for i := 0; i < 3; i++ {
+		if err := Do(func() error { return nil }, WithRetryBudget(budget)); err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+	}
+
+	// 成功的执行不消耗令牌，累积的 3 个令牌都可以用于重试
+	for i := 0; i < 3; i++ {
+		if !budget.Allow() {
+			t.Fatalf("Allow %d denied, want 3 tokens", i)
+		}
+	}
+	if budget.Allow() {
+		t.Error("Allow succeeded after tokens were spent")
+	}
+}
@@ -0,0 +1,67 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker 熔断器接口，用于在依赖明显不可用时停止重试
+type CircuitBreaker interface {
+	// Allow 判断是否允许执行下一次尝试
+	Allow() bool
+	// RecordSuccess 记录一次成功
+	RecordSuccess()
+	// RecordFailure 记录一次失败
+	RecordFailure()
+}
+
+// ConsecutiveBreaker 基于连续失败次数的简单熔断器，可并发使用
+// 连续失败达到阈值后熔断，冷却时间过后允许尝试，成功则恢复，失败则重新熔断
+type ConsecutiveBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+}
+
+// NewConsecutiveBreaker 创建新的连续失败熔断器，threshold 不大于 0 时按 1 处理
+func NewConsecutiveBreaker(threshold int, cooldown time.Duration) *ConsecutiveBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &ConsecutiveBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow 实现 CircuitBreaker 接口
+func (b *ConsecutiveBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	return time.Since(b.openedAt) >= b.cooldown
+}
+
+// RecordSuccess 实现 CircuitBreaker 接口
+func (b *ConsecutiveBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+}
+
+// RecordFailure 实现 CircuitBreaker 接口
+func (b *ConsecutiveBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
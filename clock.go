@@ -0,0 +1,58 @@
+package retry
+
+import (
+	"time"
+)
+
+// Clock 提供当前时间与等待能力，默认使用真实时间
+// 主要用于在测试中注入假时钟，无需真实等待即可验证重试间隔
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// Sleep 阻塞等待指定时间
+	Sleep(d time.Duration)
+	// NewTimer 创建在指定时间后触发的定时器
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer 是 Clock 创建的定时器，语义与 *time.Timer 相同
+type Timer interface {
+	// C 返回定时器触发时写入的通道
+	C() <-chan time.Time
+	// Stop 停止定时器
+	Stop() bool
+	// Reset 重置定时器的触发时间
+	Reset(d time.Duration) bool
+}
+
+// realClock 是基于 time 包的真实时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{t: time.NewTimer(d)}
+}
+
+// realTimer 包装 *time.Timer 以实现 Timer 接口
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r realTimer) C() <-chan time.Time {
+	return r.t.C
+}
+
+func (r realTimer) Stop() bool {
+	return r.t.Stop()
+}
+
+func (r realTimer) Reset(d time.Duration) bool {
+	return r.t.Reset(d)
+}
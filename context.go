@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// attemptInfoKey 是 AttemptInfo 在上下文中的键
+type attemptInfoKey struct{}
+
+// AttemptInfo 描述当前尝试的元信息，DoWithContext 会在每次调用函数前将其注入上下文
+type AttemptInfo struct {
+	// Attempt 当前的尝试次数，从 1 开始
+	Attempt int
+	// ElapsedSinceStart 从重试开始到本次尝试的耗时
+	ElapsedSinceStart time.Duration
+	// Remaining 按 MaxAttempts 计算、本次之后还允许的尝试次数，不限次数时为 -1
+	Remaining int
+	// IsLast 按 MaxAttempts 判断本次是否为最后一次尝试，不限次数时始终为 false。
+	// 时间限制、预算等其他条件仍可能使循环在此之前结束，因此 IsLast 为 false 不保证还会重试
+	IsLast bool
+}
+
+// AttemptInfoFromContext 从上下文中获取当前尝试的元信息
+func AttemptInfoFromContext(ctx context.Context) (AttemptInfo, bool) {
+	info, ok := ctx.Value(attemptInfoKey{}).(AttemptInfo)
+	return info, ok
+}
+
+// attemptContext 是携带尝试元信息的上下文
+// 与 context.WithValue 相比，元信息不需要装箱为接口，每次尝试只需一次分配
+type attemptContext struct {
+	context.Context
+	info AttemptInfo
+}
+
+// Value 实现 context.Context 接口
+func (c *attemptContext) Value(key any) any {
+	if key == (attemptInfoKey{}) {
+		return c.info
+	}
+	return c.Context.Value(key)
+}
+
+// withAttemptInfo 返回携带尝试元信息的子上下文
+func withAttemptInfo(ctx context.Context, info AttemptInfo) context.Context {
+	return &attemptContext{Context: ctx, info: info}
+}
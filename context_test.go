@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttemptInfoIsLast(t *testing.T) {
+	var infos []AttemptInfo
+	err := DoWithContext(context.Background(), func(ctx context.Context) error {
+		info, ok := AttemptInfoFromContext(ctx)
+		if !ok {
+			return Permanent(errors.New("no attempt info"))
+		}
+		infos = append(infos, info)
+		return errBench
+	}, WithRetryAllErrors(), WithMaxAttempts(3), WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(infos))
+	}
+	for i, info := range infos {
+		wantLast := i == len(infos)-1
+		if info.Attempt != i+1 || info.Remaining != 2-i || info.IsLast != wantLast {
+			t.Errorf("attempt %d: got %+v, want Attempt=%d Remaining=%d IsLast=%v", i+1, info, i+1, 2-i, wantLast)
+		}
+	}
+}
+
+func TestAttemptInfoUnlimited(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	attempts := 0
+	err := DoWithContext(ctx, func(ctx context.Context) error {
+		info, _ := AttemptInfoFromContext(ctx)
+		if info.IsLast || info.Remaining != -1 {
+			return Permanent(errors.New("unexpected attempt info"))
+		}
+		attempts++
+		if attempts < 10 {
+			return errBench
+		}
+		return nil
+	}, WithRetryAllErrors(), WithUnlimitedAttempts(), WithBackoff(ConstantBackoff(0)))
+
+	if err != nil {
+		t.Fatalf("DoWithContext: %v", err)
+	}
+}
+
+func TestAttemptInfoSingleAttempt(t *testing.T) {
+	_ = DoWithContext(context.Background(), func(ctx context.Context) error {
+		info, ok := AttemptInfoFromContext(ctx)
+		if !ok || !info.IsLast || info.Remaining != 0 {
+			t.Errorf("got %+v (ok=%v), want IsLast with no remaining attempts", info, ok)
+		}
+		return nil
+	}, WithMaxAttempts(1))
+}
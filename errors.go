@@ -1,32 +1,264 @@
 package retry
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 )
 
+// RetryError 表示重试循环终止时返回的错误
+type RetryError struct {
+	// LastErr 最后一次尝试返回的错误，在第一次尝试之前终止时为 nil
+	LastErr error
+	// Attempts 实际执行的尝试次数
+	Attempts int
+	// Cause 终止原因，为 ErrMaxAttemptsReached 等哨兵错误之一
+	Cause error
+	// Reason 与 Cause 对应的终止原因
+	Reason TerminationReason
+	// Name 重试策略的名称，参见 WithName
+	Name string
+	// ContextCause 因上下文结束而终止时 context.Cause 返回的原因，
+	// 仅在其不同于 context.Canceled 和 context.DeadlineExceeded 时设置（例如使用 context.WithCancelCause）
+	ContextCause error
+}
+
+// newRetryError 创建新的重试错误
+func newRetryError(cause error, lastErr error, attempts int) *RetryError {
+	return &RetryError{
+		LastErr:  lastErr,
+		Attempts: attempts,
+		Cause:    cause,
+		Reason:   reasonForCause(cause),
+	}
+}
+
+// newContextError 创建因上下文结束而终止的重试错误，并记录上下文的取消原因
+func newContextError(ctx context.Context, lastErr error, attempts int) *RetryError {
+	e := newRetryError(contextCause(ctx), lastErr, attempts)
+	e.setContextCause(ctx)
+	return e
+}
+
+// setContextCause 在终止原因为上下文结束时记录 context.Cause 提供的具体原因
+func (e *RetryError) setContextCause(ctx context.Context) {
+	if e.Cause != ErrContextCanceled && e.Cause != ErrContextDeadlineExceeded {
+		return
+	}
+	if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() {
+		e.ContextCause = cause
+	}
+}
+
+// Error 实现 error 接口
+func (e *RetryError) Error() string {
+	msg := e.Cause.Error()
+	if e.ContextCause != nil {
+		msg += ": " + e.ContextCause.Error()
+	}
+	if e.Name != "" {
+		verb := "stopped"
+		if e.Reason == MaxAttempts {
+			verb = "exhausted"
+		}
+		msg = fmt.Sprintf("retry '%s' %s after %d attempts: %s", e.Name, verb, e.Attempts, msg)
+	}
+
+	if e.LastErr == nil {
+		return msg
+	}
+	return msg + "\n" + e.LastErr.Error()
+}
+
+// Unwrap 返回终止原因、上下文的取消原因和最后一次错误，使 errors.Is 和 errors.As 可以同时匹配它们
+func (e *RetryError) Unwrap() []error {
+	errs := []error{e.Cause}
+	if e.ContextCause != nil {
+		errs = append(errs, e.ContextCause)
+	}
+	if e.LastErr != nil {
+		errs = append(errs, e.LastErr)
+	}
+	return errs
+}
+
+// unrecoverableError 表示不应再重试的错误
+type unrecoverableError struct {
+	err error
+}
+
+// Error 实现 error 接口
+func (e *unrecoverableError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *unrecoverableError) Unwrap() error {
+	return e.err
+}
+
+// Unrecoverable 包装错误，使重试循环不再调用 IsRetryable，立即返回原始错误
+// 返回值仍可通过 errors.Is 和 errors.As 匹配原始错误
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &unrecoverableError{err: err}
+}
+
+// unrecoverableCause 返回被 Unrecoverable 包装的原始错误，未被包装时返回 nil
+func unrecoverableCause(err error) error {
+	var u *unrecoverableError
+	if errors.As(err, &u) {
+		return u.err
+	}
+	return nil
+}
+
+// PermanentError 表示永久性错误，重试循环遇到该错误时立即返回其包装的错误
+// 与 Unrecoverable 不同，PermanentError 是导出类型，中间层可以通过 errors.As 识别并处理
+type PermanentError struct {
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *PermanentError) Error() string {
+	if e.Err == nil {
+		return "permanent error"
+	}
+	return e.Err.Error()
+}
+
+// Unwrap 返回被包装的错误
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent 将错误包装为永久性错误
+// err 为 nil 时重试循环将返回值视为成功，与 Unrecoverable(nil) 返回 nil 一致
+func Permanent(err error) *PermanentError {
+	return &PermanentError{Err: err}
+}
+
+// isNilPermanent 判断错误链中是否存在没有包装任何错误的 PermanentError
+func isNilPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p) && (p == nil || p.Err == nil)
+}
+
+// PanicError 表示函数执行时发生的 panic，由 WithRecoverPanics 捕获
+type PanicError struct {
+	// Value recover 得到的值
+	Value any
+	// Stack 发生 panic 时的调用栈
+	Stack []byte
+}
+
+// Error 实现 error 接口
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap 在 panic 的值为 error 时返回该错误
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
 // IsNetworkError 判断是否为网络错误
+// 使用 errors.As 和 errors.Is 沿错误链检查，支持 errors.Join 等包含多个错误的节点以及自定义的 Is/As 方法
 func IsNetworkError(err error) bool {
 	if err == nil {
 		return false
 	}
 
+	// 证书校验失败是永久性错误，不可重试
+	if isCertificateError(err) {
+		return false
+	}
+
+	// DNS 错误中 "no such host" 是永久性错误，不可重试
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return false
+		}
+		if dnsErr.IsTimeout || dnsErr.IsTemporary {
+			return true
+		}
+	}
+
+	// 收到非 TLS 数据，通常是负载均衡器等中间设备的瞬时异常
+	var recordErr *tls.RecordHeaderError
+	if errors.As(err, &recordErr) {
+		return true
+	}
+
+	// HTTP 客户端返回的 *url.Error 的 Timeout 只检查直接包装的错误，需要单独检查其内部错误
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Err != nil && IsNetworkError(urlErr.Err) {
+		return true
+	}
+
 	var netErr net.Error
-	if errors.As(err, &netErr) {
-		return netErr.Timeout() || netErr.Temporary()
+	if errors.As(err, &netErr) && (netErr.Timeout() || netErr.Temporary()) {
+		return true
 	}
 
 	// 检查常见的网络错误
-	if errors.Is(err, syscall.ECONNRESET) ||
+	return errors.Is(err, syscall.ECONNRESET) ||
 		errors.Is(err, syscall.ECONNABORTED) ||
 		errors.Is(err, syscall.ECONNREFUSED) ||
-		errors.Is(err, syscall.ETIMEDOUT) {
+		errors.Is(err, syscall.ETIMEDOUT)
+}
+
+// IsRetryableIOError 判断读写数据流时的 I/O 错误是否可重试，通常只适用于幂等的读操作
+// io.ErrUnexpectedEOF 和 io.ErrClosedPipe（包括被包装的形式）可重试。
+// io.EOF 通常表示数据流正常结束，无法与连接中断区分，为避免掩盖正常完成，不视为可重试。
+// 可以与其他判断函数组合使用，例如 AnyRetryable(DefaultRetryable, IsRetryableIOError)
+func IsRetryableIOError(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.ErrClosedPipe)
+}
+
+// IsRetryableTLSError 判断 TLS 错误是否可重试
+// 握手超时和 *tls.RecordHeaderError（收到非 TLS 数据）可重试；证书校验失败是永久性错误，不可重试。
+// IsNetworkError 同样能识别这些错误，该函数只判断 TLS 相关的错误，其余错误均返回 false
+func IsRetryableTLSError(err error) bool {
+	if isCertificateError(err) {
+		return false
+	}
+
+	var recordErr *tls.RecordHeaderError
+	if errors.As(err, &recordErr) {
 		return true
 	}
 
-	return false
+	// net/http 的 TLS 握手超时错误实现了 net.Error
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() && strings.Contains(err.Error(), "TLS handshake")
+}
+
+// isCertificateError 判断是否为证书校验失败
+func isCertificateError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &certErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
 }
 
 // IsHTTPRetryable 判断HTTP错误是否可重试
@@ -35,6 +267,16 @@ func IsHTTPRetryable(statusCode int) bool {
 	return statusCode >= 500 || statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout
 }
 
+// IsIdempotentMethod 判断 HTTP 方法是否幂等（参见 RFC 9110）
+func IsIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
 // IsRetryableHTTPError 判断HTTP错误是否可重试
 func IsRetryableHTTPError(err error) bool {
 	if err == nil {
@@ -55,10 +297,120 @@ func IsRetryableHTTPError(err error) bool {
 	return false
 }
 
+// DefaultRetryable 是推荐使用的错误判断函数
+// 网络错误和可重试的 HTTP 错误可重试；调用方上下文的取消和超时不可重试；其余错误均不可重试
+// 包装在 *url.Error 中的超时（如 http.Client.Timeout）按网络错误处理，调用方上下文结束由重试循环自行检查
+func DefaultRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return false
+	}
+
+	return IsRetryableHTTPError(err)
+}
+
+// RetryOnErrors 返回仅对指定错误重试的判断函数，使用 errors.Is 进行匹配
+func RetryOnErrors(errs ...error) IsRetryableFunc {
+	return func(err error) bool {
+		for _, target := range errs {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AbortOnErrors 返回对指定错误不重试、其余错误均重试的判断函数，使用 errors.Is 进行匹配
+func AbortOnErrors(errs ...error) IsRetryableFunc {
+	return func(err error) bool {
+		for _, target := range errs {
+			if errors.Is(err, target) {
+				return false
+			}
+		}
+		return err != nil
+	}
+}
+
+// AnyRetryable 组合多个判断函数，任意一个返回 true 即可重试，nil 会被跳过
+func AnyRetryable(fns ...IsRetryableFunc) IsRetryableFunc {
+	return func(err error) bool {
+		for _, fn := range fns {
+			if fn != nil && fn(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllRetryable 组合多个判断函数，全部返回 true 才可重试，nil 会被跳过
+func AllRetryable(fns ...IsRetryableFunc) IsRetryableFunc {
+	return func(err error) bool {
+		for _, fn := range fns {
+			if fn != nil && !fn(err) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NotRetryable 返回对判断结果取反的判断函数
+func NotRetryable(fn IsRetryableFunc) IsRetryableFunc {
+	return func(err error) bool {
+		return !fn(err)
+	}
+}
+
+// HTTPStatusRetryable 返回仅对指定状态码的 *HTTPError 重试的判断函数
+// 可与 AnyRetryable(IsNetworkError, ...) 组合以同时重试网络错误
+func HTTPStatusRetryable(codes ...int) IsRetryableFunc {
+	set := make(map[int]struct{}, len(codes))
+	for _, code := range codes {
+		set[code] = struct{}{}
+	}
+
+	return func(err error) bool {
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			return false
+		}
+		_, ok := set[httpErr.StatusCode]
+		return ok
+	}
+}
+
+// HTTPStatusRange 返回 [from, to] 范围内的所有状态码，便于与 HTTPStatusRetryable 组合使用
+// 例如 HTTPStatusRetryable(append(HTTPStatusRange(500, 599), http.StatusConflict)...)
+func HTTPStatusRange(from, to int) []int {
+	if to < from {
+		return nil
+	}
+
+	codes := make([]int, 0, to-from+1)
+	for code := from; code <= to; code++ {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
 // HTTPError 表示 HTTP 错误
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	// RetryAfter 服务端通过 Retry-After 头建议的重试间隔，为 0 表示未提供
+	RetryAfter time.Duration
+	// Response 原始的 HTTP 响应，可用于读取响应头，可能为 nil。
+	// 本库不会读取或关闭响应体，调用方仍需负责消费并关闭 Response.Body
+	Response *http.Response
+	// Err 被包装的底层错误，可能为 nil
+	Err error
 }
 
 // Error 实现 error 接口
@@ -66,6 +418,11 @@ func (e *HTTPError) Error() string {
 	return e.Message
 }
 
+// Unwrap 返回被包装的底层错误
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
 // NewHTTPError 创建新的 HTTP 错误
 func NewHTTPError(statusCode int, message string) *HTTPError {
 	return &HTTPError{
@@ -73,3 +430,134 @@ func NewHTTPError(statusCode int, message string) *HTTPError {
 		Message:    message,
 	}
 }
+
+// NewHTTPErrorWithRetryAfter 创建携带 Retry-After 间隔的 HTTP 错误
+func NewHTTPErrorWithRetryAfter(statusCode int, message string, retryAfter time.Duration) *HTTPError {
+	return &HTTPError{
+		StatusCode: statusCode,
+		Message:    message,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewHTTPErrorFromResponse 根据 HTTP 响应创建 HTTP 错误，并从响应头中解析 Retry-After
+// 返回的错误会持有 resp，调用方仍需负责消费并关闭 resp.Body
+func NewHTTPErrorFromResponse(resp *http.Response, message string) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		Response:   resp,
+	}
+	if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		httpErr.RetryAfter = d
+	}
+	return httpErr
+}
+
+// CheckResponse 检查 HTTP 响应，状态码可重试时返回 *HTTPError，否则返回 nil
+// retryableCodes 为空时使用 IsHTTPRetryable 判断。返回的错误会解析响应头中的 Retry-After，
+// 并持有 resp，调用方仍需负责消费并关闭 resp.Body
+func CheckResponse(resp *http.Response, retryableCodes ...int) error {
+	retryable := IsHTTPRetryable(resp.StatusCode)
+	if len(retryableCodes) > 0 {
+		retryable = false
+		for _, code := range retryableCodes {
+			if resp.StatusCode == code {
+				retryable = true
+				break
+			}
+		}
+	}
+	if !retryable {
+		return nil
+	}
+
+	message := fmt.Sprintf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	return NewHTTPErrorFromResponse(resp, message)
+}
+
+// RetryableResponse 使用与 DefaultRetryable 相同的分类逻辑判断一次 HTTP 请求的结果是否应该重试
+// err 不为 nil 时按错误判断；否则按 resp 的状态码判断，可重试时 delay 为 Retry-After 头建议的等待时间，
+// 没有或无法解析时为 0，表示由调用方自行决定退避。该函数不会休眠，也不会读取或修改 resp
+func RetryableResponse(resp *http.Response, err error) (retry bool, delay time.Duration) {
+	if err != nil {
+		return DefaultRetryable(err), 0
+	}
+	if resp == nil || !IsHTTPRetryable(resp.StatusCode) {
+		return false, 0
+	}
+
+	if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		delay = d
+	}
+	return true, delay
+}
+
+// ParseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式
+// 日期早于当前时间时返回 0，秒数超出 time.Duration 的范围时返回最大值，无法解析时第二个返回值为 false
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > int64(math.MaxInt64/time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	d := time.Until(t)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// RetryAfterMode 服务端建议的间隔（Retry-After）与本地计算的间隔的组合方式
+type RetryAfterMode int
+
+const (
+	// PreferServer 直接使用服务端建议的间隔
+	PreferServer RetryAfterMode = iota
+	// MaxOfServerAndLocal 取服务端建议的间隔与本地间隔（含抖动）中较大的一个
+	MaxOfServerAndLocal
+	// ClampServerToMax 使用服务端建议的间隔，但不超过 MaxRetryAfter
+	ClampServerToMax
+)
+
+// combine 按模式组合服务端建议的间隔与本地间隔
+func (m RetryAfterMode) combine(server time.Duration, local func() time.Duration, max time.Duration) time.Duration {
+	switch m {
+	case MaxOfServerAndLocal:
+		if l := local(); l > server {
+			return l
+		}
+		return server
+	case ClampServerToMax:
+		if max > 0 && server > max {
+			return max
+		}
+		return server
+	default:
+		return server
+	}
+}
+
+// retryAfter 返回错误中携带的 Retry-After 间隔
+func retryAfter(err error) (time.Duration, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		return httpErr.RetryAfter, true
+	}
+	return 0, false
+}
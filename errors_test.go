@@ -0,0 +1,383 @@
+package retry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// resetError 通过自定义的 Is 方法表示连接被重置
+type resetError struct{}
+
+func (resetError) Error() string { return "connection reset" }
+
+func (resetError) Is(target error) bool { return target == syscall.ECONNRESET }
+
+func TestIsNetworkErrorJoined(t *testing.T) {
+	errOther := errors.New("validation failed")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"reset last", errors.Join(errOther, syscall.ECONNRESET), true},
+		{"reset first", errors.Join(syscall.ECONNREFUSED, errOther), true},
+		{"wrapped join", fmt.Errorf("batch: %w", errors.Join(errOther, syscall.ETIMEDOUT)), true},
+		{"no network error", errors.Join(errOther, errors.New("other")), false},
+		{"custom Is", resetError{}, true},
+		{"joined custom Is", errors.Join(errOther, resetError{}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNetworkError(tt.err); got != tt.want {
+				t.Errorf("IsNetworkError() = %v, want %v", got, tt.want)
+			}
+			if got := IsRetryableHTTPError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableHTTPError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermanentNilIsSuccess(t *testing.T) {
+	for name, ret := range map[string]error{
+		"bare":    Permanent(nil),
+		"wrapped": fmt.Errorf("x: %w", Permanent(nil)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			stats, err := DoWithStats(func() error {
+				calls++
+				return ret
+			})
+
+			if err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if !stats.Succeeded || stats.Reason != Succeeded {
+				t.Errorf("stats = %+v, want Succeeded", stats)
+			}
+			if calls != 1 {
+				t.Errorf("calls = %d, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestPermanentStopsRetrying(t *testing.T) {
+	errFatal := errors.New("fatal")
+	calls := 0
+	err := DoWithContext(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Permanent(errFatal)
+	}, WithRetryAllErrors())
+
+	if err != errFatal {
+		t.Fatalf("err = %v, want %v", err, errFatal)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryOnErrorsWrapped(t *testing.T) {
+	isRetryable := RetryOnErrors(io.EOF, io.ErrUnexpectedEOF)
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{io.EOF, true},
+		{fmt.Errorf("read body: %w", io.EOF), true},
+		{fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", io.ErrUnexpectedEOF)), true},
+		{io.ErrClosedPipe, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("RetryOnErrors(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAbortOnErrorsWrapped(t *testing.T) {
+	isRetryable := AbortOnErrors(io.EOF)
+
+	if isRetryable(fmt.Errorf("read body: %w", io.EOF)) {
+		t.Error("wrapped io.EOF should abort")
+	}
+	if !isRetryable(io.ErrUnexpectedEOF) {
+		t.Error("io.ErrUnexpectedEOF should be retryable")
+	}
+}
+
+func TestIsNetworkErrorURLAndDNS(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"dns timeout", &url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{IsTimeout: true}}, true},
+		{"dns temporary", &url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{IsTemporary: true}}, true},
+		{"dns not found", &url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{"wrapped dns timeout", fmt.Errorf("fetch: %w", &url.Error{Op: "Get", Err: fmt.Errorf("dial: %w", &net.DNSError{IsTimeout: true})}), true},
+		{"connection reset", &url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, true},
+		{"wrapped connection reset", &url.Error{Op: "Get", Err: fmt.Errorf("read: %w", syscall.ECONNRESET)}, true},
+		{"plain url error", &url.Error{Op: "Get", Err: errors.New("unsupported protocol scheme")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNetworkError(tt.err); got != tt.want {
+				t.Errorf("IsNetworkError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusRetryable(t *testing.T) {
+	isRetryable := HTTPStatusRetryable(http.StatusConflict, http.StatusServiceUnavailable)
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{NewHTTPError(http.StatusConflict, "conflict"), true},
+		{fmt.Errorf("update: %w", NewHTTPError(http.StatusServiceUnavailable, "unavailable")), true},
+		{NewHTTPError(http.StatusInternalServerError, "internal"), false},
+		{NewHTTPError(http.StatusTooManyRequests, "too many requests"), false},
+		{errors.New("plain"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("HTTPStatusRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPStatusRange(t *testing.T) {
+	isRetryable := HTTPStatusRetryable(HTTPStatusRange(500, 599)...)
+
+	if !isRetryable(NewHTTPError(599, "")) || !isRetryable(NewHTTPError(500, "")) {
+		t.Error("range bounds should be retryable")
+	}
+	if isRetryable(NewHTTPError(499, "")) || isRetryable(NewHTTPError(600, "")) {
+		t.Error("codes outside the range should not be retryable")
+	}
+	if HTTPStatusRange(600, 500) != nil {
+		t.Error("inverted range should be empty")
+	}
+}
+
+func response(code int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: code, Header: header, Body: http.NoBody}
+}
+
+func TestCheckResponse(t *testing.T) {
+	if err := CheckResponse(response(http.StatusOK, nil)); err != nil {
+		t.Errorf("200: err = %v, want nil", err)
+	}
+
+	var httpErr *HTTPError
+	if err := CheckResponse(response(http.StatusServiceUnavailable, nil)); !errors.As(err, &httpErr) {
+		t.Fatalf("503: err = %v, want *HTTPError", err)
+	}
+	if httpErr.StatusCode != http.StatusServiceUnavailable || httpErr.RetryAfter != 0 {
+		t.Errorf("503: got %+v", httpErr)
+	}
+
+	resp := response(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"3"}})
+	if err := CheckResponse(resp); !errors.As(err, &httpErr) {
+		t.Fatalf("429: err = %v, want *HTTPError", err)
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests || httpErr.RetryAfter != 3*time.Second || httpErr.Response != resp {
+		t.Errorf("429: got %+v", httpErr)
+	}
+}
+
+func TestCheckResponseCustomCodes(t *testing.T) {
+	if err := CheckResponse(response(http.StatusServiceUnavailable, nil), http.StatusConflict); err != nil {
+		t.Errorf("503 with custom set: err = %v, want nil", err)
+	}
+	if err := CheckResponse(response(http.StatusConflict, nil), http.StatusConflict); err == nil {
+		t.Error("409 with custom set: err = nil, want *HTTPError")
+	}
+}
+
+func TestRetryableResponse(t *testing.T) {
+	netErr := &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}
+
+	tests := []struct {
+		name      string
+		resp      *http.Response
+		err       error
+		wantRetry bool
+		wantDelay time.Duration
+	}{
+		{"network error", nil, netErr, true, 0},
+		{"canceled", nil, context.Canceled, false, 0},
+		{"503 with Retry-After", response(http.StatusServiceUnavailable, http.Header{"Retry-After": []string{"2"}}), nil, true, 2 * time.Second},
+		{"503 without Retry-After", response(http.StatusServiceUnavailable, nil), nil, true, 0},
+		{"200", response(http.StatusOK, nil), nil, false, 0},
+		{"404", response(http.StatusNotFound, nil), nil, false, 0},
+		{"nil response", nil, nil, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, delay := RetryableResponse(tt.resp, tt.err)
+			if retry != tt.wantRetry || delay != tt.wantDelay {
+				t.Errorf("RetryableResponse() = (%v, %v), want (%v, %v)", retry, delay, tt.wantRetry, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestIsRetryableIOError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"wrapped unexpected EOF", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"closed pipe", io.ErrClosedPipe, true},
+		{"wrapped closed pipe", fmt.Errorf("write: %w", io.ErrClosedPipe), true},
+		{"EOF", io.EOF, false},
+		{"wrapped EOF", fmt.Errorf("read: %w", io.EOF), false},
+		{"other", errors.New("other"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableIOError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableIOError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableIOErrorComposes(t *testing.T) {
+	isRetryable := AnyRetryable(DefaultRetryable, IsRetryableIOError)
+
+	if !isRetryable(fmt.Errorf("stream: %w", io.ErrUnexpectedEOF)) {
+		t.Error("unexpected EOF should be retryable")
+	}
+	if !isRetryable(syscall.ECONNRESET) {
+		t.Error("connection reset should be retryable")
+	}
+	if isRetryable(io.EOF) {
+		t.Error("io.EOF should not be retryable")
+	}
+}
+
+// handshakeTimeoutError 模拟 net/http 在 TLS 握手超时时返回的错误
+type handshakeTimeoutError struct{}
+
+func (handshakeTimeoutError) Timeout() bool   { return true }
+func (handshakeTimeoutError) Temporary() bool { return true }
+func (handshakeTimeoutError) Error() string   { return "net/http: TLS handshake timeout" }
+
+func TestIsRetryableTLSError(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: err}
+	}
+	unknownAuthority := x509.UnknownAuthorityError{}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"handshake timeout", wrap(handshakeTimeoutError{}), true},
+		{"record header", wrap(&tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), true},
+		{"unknown authority", wrap(unknownAuthority), false},
+		{"verification error", wrap(&tls.CertificateVerificationError{Err: unknownAuthority}), false},
+		{"hostname mismatch", wrap(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "example.com"}), false},
+		{"expired", wrap(x509.CertificateInvalidError{Reason: x509.Expired}), false},
+		{"other timeout", wrap(&net.OpError{Op: "dial", Err: syscall.ETIMEDOUT}), false},
+		{"plain", errors.New("plain"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableTLSError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableTLSError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNetworkErrorTLS(t *testing.T) {
+	if !IsNetworkError(&tls.RecordHeaderError{Msg: "bad record"}) {
+		t.Error("record header error should be a network error")
+	}
+	if !IsNetworkError(&url.Error{Op: "Get", Err: handshakeTimeoutError{}}) {
+		t.Error("handshake timeout should be a network error")
+	}
+
+	// 证书校验失败即使同时表现为超时也不可重试
+	certErr := &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}
+	if IsNetworkError(errors.Join(certErr, handshakeTimeoutError{})) {
+		t.Error("certificate failure should not be retryable")
+	}
+	if DefaultRetryable(&url.Error{Op: "Get", Err: certErr}) {
+		t.Error("DefaultRetryable should reject certificate failures")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"seconds", "5", 5 * time.Second, true},
+		{"huge seconds clamped", "99999999999999", math.MaxInt64, true},
+		{"negative", "-1", 0, false},
+		{"past date", "Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+		{"invalid", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseRetryAfter(tt.value)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ParseRetryAfter(%q) = (%v, %v), want (%v, %v)", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	got, ok := ParseRetryAfter(date)
+	if !ok || got <= 8*time.Second || got > 10*time.Second {
+		t.Errorf("ParseRetryAfter(%q) = (%v, %v), want about 10s", date, got, ok)
+	}
+}
+
+func TestHugeRetryAfterRespectsMaxElapsedTime(t *testing.T) {
+	resp := response(http.StatusServiceUnavailable, http.Header{"Retry-After": []string{"99999999999999"}})
+	calls := 0
+	err := Do(func() error {
+		calls++
+		return NewHTTPErrorFromResponse(resp, "unavailable")
+	}, WithRespectRetryAfter(), WithMaxElapsedTime(time.Second), WithClock(newFakeClock()))
+
+	if !errors.Is(err, ErrMaxElapsedTimeExceeded) {
+		t.Fatalf("err = %v, want ErrMaxElapsedTimeExceeded", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
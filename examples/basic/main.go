@@ -17,6 +17,7 @@ func main() {
 			return fmt.Errorf("操作失败")
 		},
 		retry.WithMaxAttempts(3),
+		retry.WithRetryAllErrors(),
 		retry.WithBackoff(retry.ConstantBackoff(1*time.Second)),
 		retry.WithOnRetry(func(attempt int, err error) {
 			fmt.Printf("第 %d 次重试，错误: %v\n", attempt, err)
@@ -33,8 +33,8 @@ func main() {
 			}
 			defer resp.Body.Close()
 
-			if resp.StatusCode >= 500 {
-				return retry.NewHTTPError(resp.StatusCode, "服务器错误")
+			if err := retry.CheckResponse(resp); err != nil {
+				return err
 			}
 
 			fmt.Println("请求成功，状态码:", resp.StatusCode)
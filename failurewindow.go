@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"time"
+)
+
+// failureWindow 记录单次重试执行中各次尝试的结果，用于计算滑动窗口内的失败率
+type failureWindow struct {
+	window    time.Duration
+	threshold float64
+	start     time.Time
+	samples   []failureSample
+}
+
+// failureSample 是一次尝试的结果
+type failureSample struct {
+	at     time.Time
+	failed bool
+}
+
+// newFailureWindow 创建从 start 开始统计的滑动窗口
+func newFailureWindow(window time.Duration, threshold float64, start time.Time) *failureWindow {
+	return &failureWindow{window: window, threshold: threshold, start: start}
+}
+
+// record 记录一次尝试的结果并移除窗口之外的记录
+// ErrConditionNotMet 表示依赖正常响应但条件尚未满足，不计为失败
+func (w *failureWindow) record(now time.Time, err error) {
+	w.samples = append(w.samples, failureSample{
+		at:     now,
+		failed: err != nil && !conditionNotMet(err),
+	})
+
+	cutoff := now.Add(-w.window)
+	i := 0
+	for i < len(w.samples) && !w.samples[i].at.After(cutoff) {
+		i++
+	}
+	w.samples = w.samples[i:]
+}
+
+// exceeded 判断窗口内的失败率是否达到阈值
+// 为避免样本过少时过早终止，从开始执行起经过一个完整窗口之后才会判断
+func (w *failureWindow) exceeded(now time.Time) bool {
+	if now.Sub(w.start) < w.window || len(w.samples) == 0 {
+		return false
+	}
+
+	failures := 0
+	for _, s := range w.samples {
+		if s.failed {
+			failures++
+		}
+	}
+	return float64(failures)/float64(len(w.samples)) >= w.threshold
+}
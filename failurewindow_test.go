@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// runFailureRate 以每 3 次尝试中 failed 次失败的比例执行 DoUntil，其余尝试报告条件尚未满足
+func runFailureRate(failed int) (calls int, err error) {
+	return runFailureRateWith(failed, WithFailureWindow(100*time.Millisecond, 0.5))
+}
+
+func runFailureRateWith(failed int, window Option) (calls int, err error) {
+	err = DoUntil(func() (bool, error) {
+		calls++
+		if calls%3 < failed {
+			return false, errors.New("unavailable")
+		}
+		return false, nil
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(30),
+		WithBackoff(ConstantBackoff(10*time.Millisecond)),
+		WithClock(newFakeClock()),
+		window,
+	)
+	return calls, err
+}
+
+func TestFailureWindowExceeded(t *testing.T) {
+	calls, err := runFailureRate(2)
+
+	if !errors.Is(err, ErrFailureRateExceeded) {
+		t.Fatalf("err = %v, want ErrFailureRateExceeded", err)
+	}
+	// 第 11 次尝试时经过了一个完整窗口（100ms），此时才开始判断
+	if calls != 11 {
+		t.Errorf("calls = %d, want 11", calls)
+	}
+}
+
+func TestFailureWindowBelowThreshold(t *testing.T) {
+	calls, err := runFailureRate(1)
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	if calls != 30 {
+		t.Errorf("calls = %d, want 30", calls)
+	}
+}
+
+func TestFailureWindowInvalidIgnored(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"zero threshold":     WithFailureWindow(100*time.Millisecond, 0),
+		"threshold above 1":  WithFailureWindow(100*time.Millisecond, 1.5),
+		"zero window":        WithFailureWindow(0, 0.5),
+		"negative threshold": WithFailureWindow(100*time.Millisecond, -1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			calls, err := runFailureRateWith(0, opt)
+
+			if !errors.Is(err, ErrMaxAttemptsReached) {
+				t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+			}
+			if calls != 30 {
+				t.Errorf("calls = %d, want 30", calls)
+			}
+		})
+	}
+}
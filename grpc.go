@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"reflect"
+)
+
+// gRPC 状态码，与 google.golang.org/grpc/codes 中的取值一致
+const (
+	grpcCodeDeadlineExceeded  = 4
+	grpcCodeResourceExhausted = 8
+	grpcCodeAborted           = 10
+	grpcCodeUnavailable       = 14
+)
+
+// IsRetryableGRPCError 判断 gRPC 错误是否可重试
+// Unavailable、ResourceExhausted、Aborted 和 DeadlineExceeded 可重试，其余状态码不可重试。
+// 为避免引入 gRPC 依赖，这里通过错误的 GRPCStatus().Code() 方法获取状态码，
+// 与 status.FromError 一样支持被包装的错误
+func IsRetryableGRPCError(err error) bool {
+	code, ok := grpcCode(err)
+	if !ok {
+		return false
+	}
+
+	switch code {
+	case grpcCodeUnavailable, grpcCodeResourceExhausted, grpcCodeAborted, grpcCodeDeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// grpcCode 在错误链中查找实现了 GRPCStatus() 方法的错误并返回其状态码
+func grpcCode(err error) (uint64, bool) {
+	if err == nil {
+		return 0, false
+	}
+
+	if code, ok := grpcStatusCode(err); ok {
+		return code, true
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return grpcCode(e.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if code, ok := grpcCode(inner); ok {
+				return code, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// grpcStatusCode 调用 err.GRPCStatus().Code() 获取状态码
+func grpcStatusCode(err error) (uint64, bool) {
+	method := reflect.ValueOf(err).MethodByName("GRPCStatus")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return 0, false
+	}
+
+	status := method.Call(nil)[0]
+	code := status.MethodByName("Code")
+	if !code.IsValid() || code.Type().NumIn() != 0 || code.Type().NumOut() != 1 {
+		return 0, false
+	}
+
+	value := code.Call(nil)[0]
+	switch value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// fakeCode、fakeStatus 和 fakeGRPCError 模拟 grpc/codes.Code、*status.Status 和 status 包返回的错误
+type fakeCode uint32
+
+type fakeStatus struct {
+	code fakeCode
+}
+
+func (s *fakeStatus) Code() fakeCode { return s.code }
+
+type fakeGRPCError struct {
+	status *fakeStatus
+}
+
+func (e *fakeGRPCError) Error() string { return fmt.Sprintf("rpc error: code = %d", e.status.code) }
+
+func (e *fakeGRPCError) GRPCStatus() *fakeStatus { return e.status }
+
+func grpcError(code fakeCode) error {
+	return &fakeGRPCError{status: &fakeStatus{code: code}}
+}
+
+func TestIsRetryableGRPCError(t *testing.T) {
+	// 与 google.golang.org/grpc/codes 中的取值一致
+	tests := []struct {
+		name string
+		code fakeCode
+		want bool
+	}{
+		{"OK", 0, false},
+		{"Canceled", 1, false},
+		{"Unknown", 2, false},
+		{"InvalidArgument", 3, false},
+		{"DeadlineExceeded", 4, true},
+		{"NotFound", 5, false},
+		{"AlreadyExists", 6, false},
+		{"PermissionDenied", 7, false},
+		{"ResourceExhausted", 8, true},
+		{"FailedPrecondition", 9, false},
+		{"Aborted", 10, true},
+		{"OutOfRange", 11, false},
+		{"Unimplemented", 12, false},
+		{"Internal", 13, false},
+		{"Unavailable", 14, true},
+		{"DataLoss", 15, false},
+		{"Unauthenticated", 16, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableGRPCError(grpcError(tt.code)); got != tt.want {
+				t.Errorf("IsRetryableGRPCError() = %v, want %v", got, tt.want)
+			}
+			wrapped := fmt.Errorf("call: %w", grpcError(tt.code))
+			if got := IsRetryableGRPCError(wrapped); got != tt.want {
+				t.Errorf("IsRetryableGRPCError(wrapped) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableGRPCErrorNonGRPC(t *testing.T) {
+	if IsRetryableGRPCError(nil) {
+		t.Error("nil should not be retryable")
+	}
+	if IsRetryableGRPCError(errors.New("plain")) {
+		t.Error("plain error should not be retryable")
+	}
+	if !IsRetryableGRPCError(errors.Join(errors.New("plain"), grpcError(14))) {
+		t.Error("joined Unavailable should be retryable")
+	}
+}
@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// DoHedged 以对冲请求的方式执行带上下文的重试函数，用于降低读请求的尾延迟
+// 每次尝试先启动一次调用，hedgeDelay 之后仍未返回时再并发启动一次，最多额外启动 maxHedges 次，
+// 任意一次成功即取消其余调用并返回 nil。本次尝试启动的调用全部失败时以最后一个错误作为本次尝试的结果，
+// 再按选项正常判断是否重试，重试时重新开始对冲。
+// 被取消的调用通过上下文通知，fn 需要响应上下文的取消才能及时退出；DoHedged 不会等待它们返回。
+// fn 会被并发调用，需要保证并发安全，通常只应对幂等的读操作使用对冲
+func DoHedged(ctx context.Context, fn RetryableFuncWithContext, hedgeDelay time.Duration, maxHedges int, opts ...Option) error {
+	options := resolveOptions(opts...)
+	if maxHedges < 0 {
+		maxHedges = 0
+	}
+
+	return run(ctx, options, func(ctx context.Context, attempt int) error {
+		return hedge(ctx, options.Clock, fn, hedgeDelay, maxHedges)
+	})
+}
+
+// hedge 执行一次对冲尝试，返回第一个成功的结果或最后一个错误
+func hedge(ctx context.Context, clock Clock, fn RetryableFuncWithContext, hedgeDelay time.Duration, maxHedges int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 通道容量等于最多的调用次数，保证被取消的调用返回时不会阻塞
+	results := make(chan error, maxHedges+1)
+	launch := func() {
+		go func() {
+			results <- fn(ctx)
+		}()
+	}
+
+	launch()
+	launched, finished := 1, 0
+
+	var timer Timer
+	var hedgeC <-chan time.Time
+	if maxHedges > 0 {
+		timer = clock.NewTimer(hedgeDelay)
+		defer timer.Stop()
+		hedgeC = timer.C()
+	}
+
+	// 上下文结束后不再启动新的调用，但仍等待已启动的调用返回
+	done := ctx.Done()
+	var lastErr error
+	for {
+		select {
+		case <-done:
+			hedgeC = nil
+			done = nil
+		case err := <-results:
+			if err == nil {
+				return nil
+			}
+			lastErr = err
+			finished++
+			if finished == launched {
+				return lastErr
+			}
+		case <-hedgeC:
+			launch()
+			launched++
+			if launched > maxHedges {
+				hedgeC = nil
+			} else {
+				timer.Reset(hedgeDelay)
+			}
+		}
+	}
+}
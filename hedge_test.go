@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoHedgedFirstSuccessCancelsLosers(t *testing.T) {
+	var calls atomic.Int32
+	loserDone := make(chan struct{})
+
+	err := DoHedged(context.Background(), func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			// 第一次调用很慢，只有被取消时才返回
+			<-ctx.Done()
+			close(loserDone)
+			return ctx.Err()
+		}
+		return nil
+	}, 5*time.Millisecond, 2)
+
+	if err != nil {
+		t.Fatalf("DoHedged: %v", err)
+	}
+	select {
+	case <-loserDone:
+	case <-time.After(time.Second):
+		t.Fatal("slow call was not cancelled")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
+
+func TestDoHedgedAllFail(t *testing.T) {
+	errSlow := errors.New("slow failure")
+	var calls atomic.Int32
+
+	err := DoHedged(context.Background(), func(ctx context.Context) error {
+		calls.Add(1)
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		return errSlow
+	}, time.Millisecond, 2, WithRetryAllErrors(), WithMaxAttempts(2), WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) || !errors.Is(err, errSlow) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached wrapping %v", err, errSlow)
+	}
+	// 每次尝试最多启动 1 + maxHedges 次调用
+	if n := calls.Load(); n < 2 || n > 6 {
+		t.Errorf("calls = %d, want within [2, 6]", n)
+	}
+}
+
+func TestDoHedgedNoHedges(t *testing.T) {
+	var calls atomic.Int32
+	err := DoHedged(context.Background(), func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, time.Millisecond, 0)
+
+	if err != nil {
+		t.Fatalf("DoHedged: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
+
+func TestDoHedgedStopsLaunchingAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	err := DoHedged(ctx, func(ctx context.Context) error {
+		calls.Add(1)
+		cancel()
+		// 忽略取消、慢慢返回，期间对冲定时器会多次触发
+		time.Sleep(50 * time.Millisecond)
+		return errors.New("slow")
+	}, 5*time.Millisecond, 5, WithRetryAllErrors())
+
+	if !errors.Is(err, ErrContextCanceled) {
+		t.Fatalf("err = %v, want ErrContextCanceled", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
@@ -0,0 +1,89 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MetricsCollector 收集重试指标的接口，可对接 Prometheus 等监控系统
+type MetricsCollector interface {
+	// IncAttempt 每次执行函数前调用
+	IncAttempt()
+	// IncSuccess 函数最终执行成功时调用，参数为总尝试次数
+	IncSuccess(attempts int)
+	// IncExhausted 重试次数或时间耗尽时调用，参数为总尝试次数
+	IncExhausted(attempts int)
+	// ObserveBackoff 每次重试等待前调用，参数为等待时间
+	ObserveBackoff(d time.Duration)
+}
+
+// NamedMetricsCollector 是 MetricsCollector 可选实现的接口
+// 通过 WithName 设置名称时，重试循环会使用 WithName 返回的收集器，例如将名称作为 Prometheus 标签
+type NamedMetricsCollector interface {
+	MetricsCollector
+	// WithName 返回带有指定名称的收集器
+	WithName(name string) MetricsCollector
+}
+
+// noopMetrics 是不做任何事情的默认指标收集器
+type noopMetrics struct{}
+
+func (noopMetrics) IncAttempt()                  {}
+func (noopMetrics) IncSuccess(attempts int)      {}
+func (noopMetrics) IncExhausted(attempts int)    {}
+func (noopMetrics) ObserveBackoff(time.Duration) {}
+
+// CountingCollector 是基于原子计数的简单指标收集器，可并发使用
+type CountingCollector struct {
+	attempts     atomic.Int64
+	successes    atomic.Int64
+	exhausted    atomic.Int64
+	backoffs     atomic.Int64
+	totalBackoff atomic.Int64
+}
+
+// IncAttempt 实现 MetricsCollector 接口
+func (c *CountingCollector) IncAttempt() {
+	c.attempts.Add(1)
+}
+
+// IncSuccess 实现 MetricsCollector 接口
+func (c *CountingCollector) IncSuccess(attempts int) {
+	c.successes.Add(1)
+}
+
+// IncExhausted 实现 MetricsCollector 接口
+func (c *CountingCollector) IncExhausted(attempts int) {
+	c.exhausted.Add(1)
+}
+
+// ObserveBackoff 实现 MetricsCollector 接口
+func (c *CountingCollector) ObserveBackoff(d time.Duration) {
+	c.backoffs.Add(1)
+	c.totalBackoff.Add(int64(d))
+}
+
+// Attempts 返回累计的尝试次数
+func (c *CountingCollector) Attempts() int64 {
+	return c.attempts.Load()
+}
+
+// Successes 返回累计的成功次数
+func (c *CountingCollector) Successes() int64 {
+	return c.successes.Load()
+}
+
+// Exhausted 返回累计的耗尽次数
+func (c *CountingCollector) Exhausted() int64 {
+	return c.exhausted.Load()
+}
+
+// Backoffs 返回累计的重试等待次数
+func (c *CountingCollector) Backoffs() int64 {
+	return c.backoffs.Load()
+}
+
+// TotalBackoff 返回累计的重试等待时间
+func (c *CountingCollector) TotalBackoff() time.Duration {
+	return time.Duration(c.totalBackoff.Load())
+}
@@ -0,0 +1,33 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Must 调用 Do，最终失败时 panic
+// 仅适用于 main、init 和测试等失败即无法继续的场景，不要在处理请求的路径中使用
+func Must(fn RetryableFunc, opts ...Option) {
+	if err := Do(fn, opts...); err != nil {
+		mustPanic(err)
+	}
+}
+
+// MustResult 调用 DoWithResult，成功时返回结果，最终失败时 panic
+// 与 Must 一样，不要在处理请求的路径中使用
+func MustResult[T any](fn func() (T, error), opts ...Option) T {
+	v, err := DoWithResult(fn, opts...)
+	if err != nil {
+		mustPanic(err)
+	}
+	return v
+}
+
+// mustPanic 以包装了 err 的错误 panic，重试终止时错误信息中包含尝试次数
+func mustPanic(err error) {
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		panic(fmt.Errorf("retry: failed after %d attempts: %w", retryErr.Attempts, err))
+	}
+	panic(fmt.Errorf("retry: %w", err))
+}
@@ -0,0 +1,35 @@
+package retry
+
+import (
+	"sync"
+)
+
+// policies 是按名称注册的全局重试策略
+var policies struct {
+	mu       sync.RWMutex
+	retryers map[string]*Retryer
+}
+
+// RegisterPolicy 以 name 注册一个重试策略，供其他包通过 Policy 按名称获取，可并发调用
+// 选项在注册时解析（包括当时的 SetDefaultOptions），并默认以 name 作为 WithName 的名称。
+// 同名策略再次注册时覆盖之前的策略，已经通过 Policy 获取的 *Retryer 不受影响
+func RegisterPolicy(name string, opts ...Option) {
+	r := New(append([]Option{WithName(name)}, opts...)...)
+
+	policies.mu.Lock()
+	defer policies.mu.Unlock()
+
+	if policies.retryers == nil {
+		policies.retryers = make(map[string]*Retryer)
+	}
+	policies.retryers[name] = r
+}
+
+// Policy 返回以 name 注册的重试策略，未注册时第二个返回值为 false
+func Policy(name string) (*Retryer, bool) {
+	policies.mu.RLock()
+	defer policies.mu.RUnlock()
+
+	r, ok := policies.retryers[name]
+	return r, ok
+}
@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func attemptsWith(r *Retryer) int {
+	calls := 0
+	_ = r.Do(func() error {
+		calls++
+		return errBench
+	})
+	return calls
+}
+
+func TestRegisterPolicy(t *testing.T) {
+	RegisterPolicy("test-register", WithRetryAllErrors(), WithMaxAttempts(2), WithBackoff(ConstantBackoff(0)))
+
+	r, ok := Policy("test-register")
+	if !ok {
+		t.Fatal("registered policy not found")
+	}
+	if n := attemptsWith(r); n != 2 {
+		t.Errorf("attempts = %d, want 2", n)
+	}
+
+	var retryErr *RetryError
+	if err := r.Do(func() error { return errBench }); !errors.As(err, &retryErr) || retryErr.Name != "test-register" {
+		t.Errorf("err = %v, want a RetryError named after the policy", err)
+	}
+}
+
+func TestPolicyUnknown(t *testing.T) {
+	if r, ok := Policy("test-unknown"); ok || r != nil {
+		t.Errorf("Policy() = (%v, %v), want (nil, false)", r, ok)
+	}
+}
+
+func TestRegisterPolicyOverwrite(t *testing.T) {
+	RegisterPolicy("test-overwrite", WithRetryAllErrors(), WithMaxAttempts(2), WithBackoff(ConstantBackoff(0)))
+	old, _ := Policy("test-overwrite")
+
+	RegisterPolicy("test-overwrite", WithRetryAllErrors(), WithMaxAttempts(4), WithBackoff(ConstantBackoff(0)))
+	current, _ := Policy("test-overwrite")
+
+	if n := attemptsWith(current); n != 4 {
+		t.Errorf("overwritten policy attempts = %d, want 4", n)
+	}
+	// 已经获取的 *Retryer 不受影响
+	if n := attemptsWith(old); n != 2 {
+		t.Errorf("previously fetched policy attempts = %d, want 2", n)
+	}
+}
+
+func TestRegisterPolicyConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("test-concurrent-%d", i%2)
+			RegisterPolicy(name, WithMaxAttempts(1))
+			if _, ok := Policy(name); !ok {
+				t.Errorf("policy %q not found", name)
+			}
+		}()
+	}
+	wg.Wait()
+}
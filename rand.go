@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	defaultRandOnce   sync.Once
+	defaultRandSource *lockedSource
+	defaultRand       *rand.Rand
+)
+
+// lockedSource 是并发安全的随机数源
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+// Int63 实现 rand.Source 接口
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+// Uint64 实现 rand.Source64 接口
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+// Seed 实现 rand.Source 接口
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// packageRand 返回包内共享的随机数生成器，首次使用时以当前时间作为种子
+// 包内的抖动计算只使用该生成器，不会影响应用程序对全局 math/rand 的使用
+func packageRand() *rand.Rand {
+	defaultRandOnce.Do(func() {
+		src := rand.NewSource(time.Now().UnixNano()).(rand.Source64)
+		defaultRandSource = &lockedSource{src: src}
+		defaultRand = rand.New(defaultRandSource)
+	})
+	return defaultRand
+}
+
+// SeedJitter 为包内共享的随机数生成器设置种子
+// 未调用时该生成器以首次使用时的当前时间作为种子，因此不同进程的抖动序列通常不同；
+// 设置固定种子可以得到可复现的抖动序列
+func SeedJitter(seed int64) {
+	packageRand()
+	defaultRandSource.Seed(seed)
+}
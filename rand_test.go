@@ -0,0 +1,30 @@
+package retry
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func jitterSequence(seed int64) []time.Duration {
+	SeedJitter(seed)
+	backoff := ExponentialBackoffWithJitter(time.Second, time.Hour, 0.5)
+
+	seq := make([]time.Duration, 10)
+	for i := range seq {
+		seq[i] = backoff(i)
+	}
+	return seq
+}
+
+func TestSeedJitter(t *testing.T) {
+	a := jitterSequence(1)
+	b := jitterSequence(2)
+	if slices.Equal(a, b) {
+		t.Errorf("sequences from different seeds are identical: %v", a)
+	}
+
+	if again := jitterSequence(1); !slices.Equal(a, again) {
+		t.Errorf("same seed produced different sequences:\n%v\n%v", a, again)
+	}
+}
@@ -0,0 +1,198 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrInvalidResult 表示结果未通过 WithValidator 设置的校验
+// 校验函数返回的错误会包装该错误，因此可以通过 errors.Is 同时判断两者
+var ErrInvalidResult = errors.New("invalid result")
+
+// WithValidator 设置 DoWithResult 系列函数的结果校验函数
+// fn 返回 (value, nil) 后会调用校验函数，返回的非 nil 错误包装为 ErrInvalidResult。
+// 未通过 WithIsRetryable 或 WithRetryAllErrors 设置判断函数时，校验错误总是会重试；
+// 设置了判断函数时由其决定，可以通过 errors.Is(err, ErrInvalidResult) 识别校验错误。
+// 如需立即终止，校验函数也可以返回 Permanent 或 Unrecoverable 包装的错误。
+// fn 返回的错误优先于校验，结果类型不是 T 时不做校验
+func WithValidator[T any](validate func(T) error) Option {
+	return func(o *Options) {
+		o.validator = func(value any) error {
+			v, ok := value.(T)
+			if !ok {
+				return nil
+			}
+			return validate(v)
+		}
+	}
+}
+
+// WithReturnLastResult 使 DoWithResult 系列函数在失败时（次数耗尽、超时等）返回 fn 最后一次产生的非零值结果，
+// 而不是 T 的零值，供可以使用"尽力而为"结果的调用方使用，返回的错误不变。
+// 所有尝试都只产生零值时仍返回零值。fn 与错误一同返回的值、被 WithValidator 拒绝的值
+// 以及 DoWithResultUntil 中未完成时的值同样会被记录
+func WithReturnLastResult() Option {
+	return func(o *Options) {
+		o.ReturnLastResult = true
+	}
+}
+
+// lastResult 记录 fn 最后一次产生的非零值结果
+type lastResult[T any] struct {
+	value T
+}
+
+// observe 在 v 不是零值时记录 v
+func (r *lastResult[T]) observe(v T) {
+	if !reflect.ValueOf(&v).Elem().IsZero() {
+		r.value = v
+	}
+}
+
+// failed 返回失败时应返回的结果
+func (r *lastResult[T]) failed(o *Options) T {
+	if o.ReturnLastResult {
+		return r.value
+	}
+	var zero T
+	return zero
+}
+
+// validate 使用选项中的校验函数校验结果，校验失败时返回包装了 ErrInvalidResult 的错误
+func (o *Options) validate(value any) error {
+	if o.validator == nil {
+		return nil
+	}
+	if err := o.validator(value); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidResult, err)
+	}
+	return nil
+}
+
+// resultOptions 解析选项，设置了校验函数且未自定义 IsRetryable 时使 ErrInvalidResult 可重试
+func resultOptions(opts ...Option) *Options {
+	options := resolveOptions(opts...)
+	if options.validator == nil || options.customRetryable {
+		return options
+	}
+
+	isRetryable := options.IsRetryable
+	options.IsRetryable = func(err error) bool {
+		return errors.Is(err, ErrInvalidResult) || isRetryable(err)
+	}
+	return options
+}
+
+// DoWithResult 执行带重试的函数，并返回最后一次成功执行的结果
+// 重试次数耗尽时返回 T 的零值（参见 WithReturnLastResult）以及包含 ErrMaxAttemptsReached 的错误
+func DoWithResult[T any](fn func() (T, error), opts ...Option) (T, error) {
+	options := resultOptions(opts...)
+
+	var result T
+	var last lastResult[T]
+	err := runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+		v, err := fn()
+		last.observe(v)
+		if err != nil {
+			return err
+		}
+		if err := options.validate(v); err != nil {
+			return err
+		}
+		result = v
+		return nil
+	})
+	if err != nil {
+		return last.failed(options), err
+	}
+
+	return result, nil
+}
+
+// DoWithResultContext 执行带上下文的重试函数，并返回最后一次成功执行的结果
+func DoWithResultContext[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	options := resultOptions(opts...)
+
+	var result T
+	var last lastResult[T]
+	err := run(ctx, options, func(ctx context.Context, attempt int) error {
+		v, err := fn(ctx)
+		last.observe(v)
+		if err != nil {
+			return err
+		}
+		if err := options.validate(v); err != nil {
+			return err
+		}
+		result = v
+		return nil
+	})
+	if err != nil {
+		return last.failed(options), err
+	}
+
+	return result, nil
+}
+
+// Capture 将返回 (T, error) 的函数适配为 RetryableFunc，供仍在使用 Do 或 Retryer 的代码重试有返回值的函数
+// 返回的指针只有在 Do 返回 nil 之后才有效，此时指向成功那次尝试的结果；失败的尝试不会修改它。
+// 返回的函数不能并发执行，也不会经过 WithValidator 校验
+func Capture[T any](fn func() (T, error)) (RetryableFunc, *T) {
+	result := new(T)
+	return func() error {
+		v, err := fn()
+		if err != nil {
+			return err
+		}
+		*result = v
+		return nil
+	}, result
+}
+
+// DoWithResultUntil 重复执行函数直到其报告完成，并返回完成时的结果
+// 返回 (value, true, nil) 时返回 value；返回 (value, false, nil) 时总是继续重试；
+// 返回非 nil 错误时按 IsRetryable 正常判断。未完成而终止时返回 T 的零值
+func DoWithResultUntil[T any](fn func() (T, bool, error), opts ...Option) (T, error) {
+	options := untilOptions(opts...)
+
+	var result T
+	var last lastResult[T]
+	err := runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+		v, done, err := fn()
+		last.observe(v)
+		if err := untilResult(done, err); err != nil {
+			return err
+		}
+		result = v
+		return nil
+	})
+	if err != nil {
+		return last.failed(options), err
+	}
+
+	return result, nil
+}
+
+// DoWithResultUntilContext 重复执行带上下文的函数直到其报告完成，并返回完成时的结果
+func DoWithResultUntilContext[T any](ctx context.Context, fn func(ctx context.Context) (T, bool, error), opts ...Option) (T, error) {
+	options := untilOptions(opts...)
+
+	var result T
+	var last lastResult[T]
+	err := run(ctx, options, func(ctx context.Context, attempt int) error {
+		v, done, err := fn(ctx)
+		last.observe(v)
+		if err := untilResult(done, err); err != nil {
+			return err
+		}
+		result = v
+		return nil
+	})
+	if err != nil {
+		return last.failed(options), err
+	}
+
+	return result, nil
+}
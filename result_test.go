@@ -0,0 +1,168 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithValidatorRetriesInvalidResults(t *testing.T) {
+	errEmpty := errors.New("empty body")
+	calls := 0
+	got, err := DoWithResult(func() (string, error) {
+		calls++
+		if calls < 3 {
+			return "", nil
+		}
+		return "ok", nil
+	},
+		WithValidator(func(s string) error {
+			if s == "" {
+				return errEmpty
+			}
+			return nil
+		}),
+		WithBackoff(ConstantBackoff(0)),
+	)
+
+	if err != nil {
+		t.Fatalf("DoWithResult: %v", err)
+	}
+	if got != "ok" || calls != 3 {
+		t.Errorf("got %q after %d calls, want %q after 3", got, calls, "ok")
+	}
+}
+
+func TestWithValidatorExhausted(t *testing.T) {
+	errEmpty := errors.New("empty body")
+	_, err := DoWithResult(func() (string, error) {
+		return "", nil
+	},
+		WithValidator(func(s string) error { return errEmpty }),
+		WithMaxAttempts(2),
+		WithBackoff(ConstantBackoff(0)),
+	)
+
+	if !errors.Is(err, ErrMaxAttemptsReached) || !errors.Is(err, ErrInvalidResult) || !errors.Is(err, errEmpty) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached wrapping ErrInvalidResult and errEmpty", err)
+	}
+}
+
+func TestWithValidatorIsRetryableCanVeto(t *testing.T) {
+	calls := 0
+	_, err := DoWithResult(func() (string, error) {
+		calls++
+		return "", nil
+	},
+		WithValidator(func(s string) error { return errors.New("empty body") }),
+		WithIsRetryable(func(err error) bool { return !errors.Is(err, ErrInvalidResult) }),
+		WithMaxAttempts(3),
+		WithBackoff(ConstantBackoff(0)),
+	)
+
+	if !errors.Is(err, ErrInvalidResult) {
+		t.Fatalf("err = %v, want ErrInvalidResult", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestWithValidatorFnErrorTakesPrecedence(t *testing.T) {
+	errFn := errors.New("bad input")
+	validated := false
+	_, err := DoWithResult(func() (int, error) {
+		return 1, errFn
+	},
+		WithValidator(func(int) error {
+			validated = true
+			return nil
+		}),
+		WithBackoff(ConstantBackoff(time.Millisecond)),
+	)
+
+	if !errors.Is(err, errFn) {
+		t.Fatalf("err = %v, want %v", err, errFn)
+	}
+	if validated {
+		t.Error("validator called although fn returned an error")
+	}
+}
+
+func TestWithReturnLastResult(t *testing.T) {
+	run := func(opts ...Option) (int, error) {
+		n := 0
+		return DoWithResult(func() (int, error) {
+			n++
+			return n, errBench
+		}, append([]Option{WithRetryAllErrors(), WithMaxAttempts(4), WithBackoff(ConstantBackoff(0))}, opts...)...)
+	}
+
+	got, err := run(WithReturnLastResult())
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	if got != 4 {
+		t.Errorf("result = %d, want 4", got)
+	}
+
+	if got, _ := run(); got != 0 {
+		t.Errorf("without WithReturnLastResult: result = %d, want 0", got)
+	}
+}
+
+func TestWithReturnLastResultKeepsLastNonZero(t *testing.T) {
+	values := []int{1, 2, 0}
+	calls := 0
+	got, err := DoWithResult(func() (int, error) {
+		v := values[calls]
+		calls++
+		return v, nil
+	},
+		WithValidator(func(v int) error { return errors.New("rejected") }),
+		WithReturnLastResult(),
+		WithMaxAttempts(len(values)),
+		WithBackoff(ConstantBackoff(0)),
+	)
+
+	if !errors.Is(err, ErrInvalidResult) {
+		t.Fatalf("err = %v, want ErrInvalidResult", err)
+	}
+	if got != 2 {
+		t.Errorf("result = %d, want 2", got)
+	}
+}
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestCapture(t *testing.T) {
+	calls := 0
+	fn, result := Capture(func() (user, error) {
+		calls++
+		if calls < 3 {
+			return user{ID: -1}, errBench
+		}
+		return user{ID: 7, Name: "gopher"}, nil
+	})
+
+	if err := Do(fn, WithRetryAllErrors(), WithBackoff(ConstantBackoff(0))); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if *result != (user{ID: 7, Name: "gopher"}) {
+		t.Errorf("result = %+v, want the value from the successful attempt", *result)
+	}
+}
+
+func TestCaptureFailedAttemptsDoNotWrite(t *testing.T) {
+	fn, result := Capture(func() (int, error) { return 42, errBench })
+
+	if err := Do(fn, WithRetryAllErrors(), WithMaxAttempts(2), WithBackoff(ConstantBackoff(0))); err == nil {
+		t.Fatal("Do: want error")
+	}
+	if *result != 0 {
+		t.Errorf("result = %d, want 0", *result)
+	}
+}
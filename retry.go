@@ -3,6 +3,10 @@ package retry
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"math"
+	"runtime/debug"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +17,24 @@ var (
 	ErrContextCanceled = errors.New("context canceled")
 	// ErrContextDeadlineExceeded 表示上下文超时
 	ErrContextDeadlineExceeded = errors.New("context deadline exceeded")
+	// ErrMaxElapsedTimeExceeded 表示超出总耗时限制
+	ErrMaxElapsedTimeExceeded = errors.New("maximum elapsed time exceeded")
+	// ErrBackoffBudgetExhausted 表示重试等待的总时间超出预算
+	ErrBackoffBudgetExhausted = errors.New("backoff budget exhausted")
+	// ErrCircuitOpen 表示熔断器处于打开状态
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrStopped 表示通过停止通道终止了重试
+	ErrStopped = errors.New("retry stopped")
+	// ErrNonIdempotentRetry 表示需要重试但操作被标记为非幂等
+	ErrNonIdempotentRetry = errors.New("retry required for non-idempotent operation")
+	// ErrRetryBudgetExhausted 表示共享的重试预算已用完
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+	// ErrUnboundedRetry 表示不限次数重试时缺少可终止循环的上下文或时间限制
+	ErrUnboundedRetry = errors.New("unlimited attempts require a cancellable context or a time budget")
+	// ErrFailureRateExceeded 表示滑动窗口内的失败率达到阈值
+	ErrFailureRateExceeded = errors.New("failure rate exceeded")
+	// ErrStopConditionMet 表示停止条件已满足
+	ErrStopConditionMet = errors.New("stop condition met")
 )
 
 // RetryableFunc 是可重试的函数类型
@@ -32,35 +54,197 @@ type Option func(*Options)
 
 // Options 包含重试的配置选项
 type Options struct {
-	// MaxAttempts 最大重试次数，默认为 3
+	// MaxAttempts 最大重试次数，默认为 3，为 0 表示不限次数
 	MaxAttempts int
+	// MaxAttemptsFunc 在每次执行开始时计算最大重试次数，设置后优先于 MaxAttempts，返回负数时使用 MaxAttempts
+	MaxAttemptsFunc func() int
+	// MaxAttemptsJitter 每次执行时最大重试次数的随机浮动范围，为 0 表示不浮动
+	MaxAttemptsJitter int
 	// Backoff 重试间隔计算函数
 	Backoff BackoffFunc
-	// IsRetryable 判断错误是否可重试的函数
+	// BackoffWithError 可感知错误的重试间隔计算函数，设置后优先于 Backoff
+	BackoffWithError func(attempt int, err error) time.Duration
+	// StatefulBackoff 有状态的重试策略，每次执行单独保存上一次的间隔，设置后优先于 Backoff
+	StatefulBackoff StatefulBackoff
+	// BackoffMultipliers 按错误调整重试间隔的倍数，所有匹配的倍数相乘后作用于本地计算的间隔
+	BackoffMultipliers []BackoffMultiplier
+	// IsRetryable 判断错误是否可重试的函数，默认为 DefaultRetryable
 	IsRetryable IsRetryableFunc
 	// OnRetry 每次重试前调用的函数
 	OnRetry func(attempt int, err error)
+	// OnSuccess 函数最终执行成功时调用的函数，参数为成功时的尝试次数（从 1 开始）
+	OnSuccess func(attempt int)
+	// BeforeAttempt 每次调用函数之前调用的函数，参数为当前的尝试次数（从 1 开始）
+	BeforeAttempt func(attempt int)
+	// AfterAttempt 每次调用函数之后调用的函数，无论成功与否，参数为当前的尝试次数和函数返回的错误
+	AfterAttempt func(attempt int, err error)
+	// OnBackoff 每次等待重试间隔之前调用的函数，参数为已执行的尝试次数和经过所有调整后的实际间隔
+	OnBackoff func(attempt int, delay time.Duration)
+	// OnGiveUp 重试次数或时间耗尽、放弃重试时调用的函数，参数为总尝试次数和将要返回的错误，
+	// err 的具体类型为 *RetryError，其 Reason 表示放弃的原因
+	OnGiveUp func(attempts int, err error)
+	// OnComplete 重试循环结束时调用的函数，参数为完整的尝试记录，为 nil 表示不记录
+	OnComplete func(summary RunSummary)
+	// BeforeRetryCleanup 确定重试之后、等待重试间隔之前调用的清理函数，为 nil 表示不清理
+	BeforeRetryCleanup func(attempt int, err error) error
+	// MaxElapsedTime 所有尝试（含重试间隔）的总耗时上限，为 0 表示不限制
+	MaxElapsedTime time.Duration
+	// MaxBackoffBudget 重试等待的总时间上限，不包括函数执行的时间，为 0 表示不限制
+	MaxBackoffBudget time.Duration
+	// AttemptTimeout 单次尝试的超时时间，仅对 DoWithContext 生效，为 0 表示不限制
+	AttemptTimeout time.Duration
+	// AttemptTimeouts 按尝试次数指定的单次尝试超时时间，最后一项用于之后的所有尝试，设置后优先于 AttemptTimeout
+	AttemptTimeouts []time.Duration
+	// ContextPerAttempt 为每次尝试派生上下文的函数，仅对 DoWithContext 等传入上下文的函数生效，为 nil 表示不派生
+	ContextPerAttempt func(parent context.Context, attempt int) (context.Context, context.CancelFunc)
+	// RespectRetryAfter 是否使用 HTTPError 中的 RetryAfter 代替计算出的重试间隔
+	RespectRetryAfter bool
+	// RetryAfterMode 服务端建议的间隔与本地计算的间隔的组合方式，默认为 PreferServer
+	RetryAfterMode RetryAfterMode
+	// MaxRetryAfter 在 ClampServerToMax 模式下服务端建议间隔的上限
+	MaxRetryAfter time.Duration
+	// RetryAfterExtendsAttempts 是否在服务端持续提供 Retry-After 时忽略 MaxAttempts，
+	// 仅在同时设置 RespectRetryAfter 与 MaxElapsedTime 时生效
+	RetryAfterExtendsAttempts bool
+	// DeadlineAwareBackoff 是否根据上下文的截止时间缩短重试间隔，仅对带截止时间的上下文生效
+	DeadlineAwareBackoff bool
+	// Idempotent 操作是否幂等，默认为 true；为 false 时需要重试会返回 ErrNonIdempotentRetry
+	Idempotent bool
+	// RetryBudget 跨调用共享的重试预算，为 nil 表示不限制
+	RetryBudget RetryBudget
+	// ErrorMapper 在其他逻辑看到错误之前对每次尝试返回的错误进行转换，为 nil 表示不转换
+	ErrorMapper func(err error) error
+	// SuccessErrors 视为成功的错误，函数返回的错误通过 errors.Is 匹配其中之一时本次尝试视为成功
+	SuccessErrors []error
+	// Name 重试策略的名称，用于区分日志、指标和错误信息，为空表示不命名
+	Name string
+	// FailureWindow 统计失败率的滑动窗口长度，为 0 表示不按失败率终止
+	FailureWindow time.Duration
+	// FailureRateThreshold 滑动窗口内触发终止的失败率，取值范围为 (0, 1]
+	FailureRateThreshold float64
+	// ReturnLastResult DoWithResult 系列函数失败时是否返回最后一个非零值结果而不是零值
+	ReturnLastResult bool
+	// InitialDelay 第一次尝试之前的等待时间，不计为重试，为 0 表示不等待
+	InitialDelay time.Duration
+	// BackoffBeforeFirst 是否在第一次尝试之前按第 0 次重试的间隔等待一次
+	BackoffBeforeFirst bool
+	// Metrics 重试指标收集器
+	Metrics MetricsCollector
+	// Context 供 Do 使用的上下文，为 nil 时 Do 不支持取消
+	Context context.Context
+	// Clock 计时与等待使用的时钟，默认为真实时间
+	Clock Clock
+	// RecoverPanics 是否将函数中的 panic 转换为 *PanicError 并按普通错误处理
+	RecoverPanics bool
+	// CircuitBreaker 熔断器，每次尝试前检查是否允许执行，为 nil 表示不使用
+	CircuitBreaker CircuitBreaker
+	// StopChannel 停止通道，关闭或写入后在下一次尝试前或等待期间终止重试
+	StopChannel <-chan struct{}
+	// StopCondition 每次尝试前检查的停止条件，返回 true 时终止重试，为 nil 表示不检查
+	StopCondition func() bool
+	// Logger 结构化日志记录器，为 nil 表示不记录日志
+	Logger *slog.Logger
+
+	// validator DoWithResult 系列函数的结果校验函数
+	validator func(value any) error
+	// customRetryable 是否通过选项显式设置了 IsRetryable
+	customRetryable bool
+}
+
+// globalDefaults 保存通过 SetDefaultOptions 设置的全局默认选项
+var globalDefaults atomic.Pointer[[]Option]
+
+// SetDefaultOptions 设置全局默认选项，未传入选项的调用都会以此为基础，单次调用的选项仍会覆盖它
+// 应在初始化阶段、任何重试开始之前调用；不传参数时恢复内置默认值
+func SetDefaultOptions(opts ...Option) {
+	defaults := append([]Option(nil), opts...)
+	globalDefaults.Store(&defaults)
 }
 
+// defaultBackoff 是默认的重试策略，在所有调用之间共享以避免每次解析选项时分配
+var defaultBackoff = ConstantBackoff(1 * time.Second)
+
 // defaultOptions 返回默认选项
 func defaultOptions() *Options {
-	return &Options{
-		MaxAttempts: 3,
-		Backoff:     ConstantBackoff(1 * time.Second),
-		IsRetryable: func(err error) bool { return err != nil },
-		OnRetry:     func(attempt int, err error) {},
+	options := &Options{
+		MaxAttempts:   3,
+		Backoff:       defaultBackoff,
+		IsRetryable:   DefaultRetryable,
+		OnRetry:       func(attempt int, err error) {},
+		OnSuccess:     func(attempt int) {},
+		OnGiveUp:      func(attempts int, err error) {},
+		BeforeAttempt: func(attempt int) {},
+		AfterAttempt:  func(attempt int, err error) {},
+		OnBackoff:     func(attempt int, delay time.Duration) {},
+		Metrics:       noopMetrics{},
+		Clock:         realClock{},
+		Idempotent:    true,
 	}
+
+	if defaults := globalDefaults.Load(); defaults != nil {
+		for _, opt := range *defaults {
+			opt(options)
+		}
+	}
+
+	return options
+}
+
+// ResolveOptions 返回在默认选项（包括 SetDefaultOptions 设置的全局默认值）上依次应用 opts 之后的选项，
+// 与 Do 等函数在执行前解析得到的选项相同，可用于在运行前校验或展示重试策略。
+// 返回值是副本，修改它不会影响任何重试；其中的函数字段与选项中传入的函数相同
+func ResolveOptions(opts ...Option) Options {
+	return *resolveOptions(opts...)
 }
 
-// WithMaxAttempts 设置最大重试次数
+// resolveOptions 在默认选项上依次应用 opts
+func resolveOptions(opts ...Option) *Options {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithMaxAttempts 设置最大重试次数，为 0 表示不限次数（参见 WithUnlimitedAttempts），负数会被忽略
 func WithMaxAttempts(attempts int) Option {
 	return func(o *Options) {
-		if attempts > 0 {
+		if attempts >= 0 {
 			o.MaxAttempts = attempts
 		}
 	}
 }
 
+// WithUnlimitedAttempts 设置不限次数重试
+// 此时循环只会因成功、不可重试的错误、上下文结束、停止信号或时间限制而终止。
+// 为避免意外的无限循环，必须同时提供可取消的上下文、WithStopChannel、WithStopCondition
+// 或 WithMaxElapsedTime / WithMaxBackoffBudget，否则直接返回 ErrUnboundedRetry
+func WithUnlimitedAttempts() Option {
+	return func(o *Options) {
+		o.MaxAttempts = 0
+	}
+}
+
+// WithMaxAttemptsFunc 设置动态计算最大重试次数的函数，例如在故障期间降低重试次数
+// 函数在每次 Do 调用开始时执行一次，返回值的含义与 WithMaxAttempts 相同：0 表示不限次数，
+// 负数表示使用静态设置的 MaxAttempts。设置后优先于 WithMaxAttempts 和 WithUnlimitedAttempts
+func WithMaxAttemptsFunc(fn func() int) Option {
+	return func(o *Options) {
+		o.MaxAttemptsFunc = fn
+	}
+}
+
+// WithMaxAttemptsJitter 使每次执行的最大重试次数在 [MaxAttempts-spread, MaxAttempts+spread] 内随机选取（不小于 1），
+// 避免大量客户端在故障期间同时放弃重试。随机值在每次 Do 调用开始时使用包内随机数生成器选取一次，
+// 作用于 WithMaxAttemptsFunc 的结果之上，不限次数时不生效
+func WithMaxAttemptsJitter(spread int) Option {
+	return func(o *Options) {
+		if spread >= 0 {
+			o.MaxAttemptsJitter = spread
+		}
+	}
+}
+
 // WithBackoff 设置重试间隔计算函数
 func WithBackoff(backoff BackoffFunc) Option {
 	return func(o *Options) {
@@ -68,10 +252,56 @@ func WithBackoff(backoff BackoffFunc) Option {
 	}
 }
 
+// WithBackoffFunc 设置可感知错误的重试间隔计算函数，可根据错误类型返回不同的间隔
+// 同时设置 WithBackoff 时优先使用该函数
+func WithBackoffFunc(fn func(attempt int, err error) time.Duration) Option {
+	return func(o *Options) {
+		o.BackoffWithError = fn
+	}
+}
+
+// WithStatefulBackoff 设置有状态的重试策略
+// 上一次的间隔由每次执行的重试循环单独保存，因此同一个策略可以在多个 goroutine 之间安全复用。
+// 同时设置 WithBackoffFunc 时优先使用可感知错误的函数
+func WithStatefulBackoff(b StatefulBackoff) Option {
+	return func(o *Options) {
+		o.StatefulBackoff = b
+	}
+}
+
+// BackoffMultiplier 描述错误匹配时重试间隔的倍数
+type BackoffMultiplier struct {
+	// Match 判断错误是否匹配
+	Match IsRetryableFunc
+	// Multiplier 匹配时重试间隔乘以的倍数
+	Multiplier float64
+}
+
+// WithBackoffMultiplierFor 设置错误匹配 match 时重试间隔乘以 multiplier，例如对 429 等限流错误退避更久
+// 可以多次调用，多个匹配的倍数相乘。倍数只作用于重试策略计算出的间隔，不影响服务端的 Retry-After，
+// 不大于 0 的倍数会被忽略
+func WithBackoffMultiplierFor(match IsRetryableFunc, multiplier float64) Option {
+	return func(o *Options) {
+		if multiplier > 0 {
+			o.BackoffMultipliers = append(o.BackoffMultipliers, BackoffMultiplier{Match: match, Multiplier: multiplier})
+		}
+	}
+}
+
 // WithIsRetryable 设置判断错误是否可重试的函数
 func WithIsRetryable(isRetryable IsRetryableFunc) Option {
 	return func(o *Options) {
 		o.IsRetryable = isRetryable
+		o.customRetryable = true
+	}
+}
+
+// WithRetryAllErrors 设置重试所有非 nil 错误
+// 这是旧版本的默认行为：确定性的错误（如参数错误）也会被重试到最大次数，仅在确有需要时使用
+func WithRetryAllErrors() Option {
+	return func(o *Options) {
+		o.IsRetryable = func(err error) bool { return err != nil }
+		o.customRetryable = true
 	}
 }
 
@@ -82,84 +312,939 @@ func WithOnRetry(onRetry func(attempt int, err error)) Option {
 	}
 }
 
-// Do 执行带重试的函数
-func Do(fn RetryableFunc, opts ...Option) error {
-	options := defaultOptions()
-	for _, opt := range opts {
-		opt(options)
+// WithOnSuccess 设置函数最终执行成功时调用的函数
+func WithOnSuccess(onSuccess func(attempt int)) Option {
+	return func(o *Options) {
+		o.OnSuccess = onSuccess
 	}
+}
 
-	var err error
-	for attempt := 0; attempt < options.MaxAttempts; attempt++ {
-		err = fn()
-		if err == nil {
-			return nil
+// WithBeforeAttempt 设置每次调用函数之前调用的函数
+// 与 OnRetry 不同，它在每次尝试（包括第一次）之前都会被调用
+func WithBeforeAttempt(fn func(attempt int)) Option {
+	return func(o *Options) {
+		o.BeforeAttempt = fn
+	}
+}
+
+// WithAfterAttempt 设置每次调用函数之后调用的函数
+// 它在每次尝试（包括最后一次和成功的一次）之后都会被调用，调用顺序为：
+// BeforeAttempt -> fn -> AfterAttempt -> OnSuccess 或 OnRetry -> 等待重试间隔
+func WithAfterAttempt(fn func(attempt int, err error)) Option {
+	return func(o *Options) {
+		o.AfterAttempt = fn
+	}
+}
+
+// WithOnBackoff 设置每次等待重试间隔之前调用的函数
+// 与 OnRetry 不同，它报告的是经过 Retry-After、截止时间等所有调整后实际将要等待的间隔
+func WithOnBackoff(fn func(attempt int, delay time.Duration)) Option {
+	return func(o *Options) {
+		o.OnBackoff = fn
+	}
+}
+
+// WithBeforeRetryCleanup 设置重试前的清理函数，用于回滚失败的尝试留下的部分修改（例如删除写了一半的临时文件）
+// 仅在失败且确定会重试时调用，时机在 OnRetry 和等待重试间隔之前，参数为已执行的尝试次数和最后一次错误。
+// 清理函数返回错误时终止重试，返回该错误与最后一次错误通过 errors.Join 合并后的错误
+func WithBeforeRetryCleanup(cleanup func(attempt int, err error) error) Option {
+	return func(o *Options) {
+		o.BeforeRetryCleanup = cleanup
+	}
+}
+
+// WithOnComplete 设置重试循环结束时（无论成功或失败）调用的函数，参数包含按顺序排列的每次尝试的错误、耗时和之后的等待间隔，
+// 适合在事后排查时一次性记录完整的执行过程。设置后每次尝试都会保存一条记录，
+// 尝试次数很多（例如不限次数重试）时内存占用随之增长
+func WithOnComplete(fn func(summary RunSummary)) Option {
+	return func(o *Options) {
+		o.OnComplete = fn
+	}
+}
+
+// WithOnGiveUp 设置放弃重试时调用的函数
+// 仅在重试次数、总耗时或间隔预算耗尽时调用一次，err 为 *RetryError，可以通过 errors.As 取得后按 Reason 区分原因；
+// 函数成功或遇到不可重试的错误时不会调用
+func WithOnGiveUp(onGiveUp func(attempts int, err error)) Option {
+	return func(o *Options) {
+		o.OnGiveUp = onGiveUp
+	}
+}
+
+// WithMaxElapsedTime 设置所有尝试（含重试间隔）的总耗时上限
+// 与 MaxAttempts 同时设置时，先达到的限制生效
+func WithMaxElapsedTime(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.MaxElapsedTime = d
+		}
+	}
+}
+
+// WithMaxBackoffBudget 设置重试等待的总时间上限
+// 与 WithMaxElapsedTime 不同，该预算只统计实际的等待时间，不包括函数执行的时间
+func WithMaxBackoffBudget(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.MaxBackoffBudget = d
+		}
+	}
+}
+
+// WithAttemptTimeout 设置单次尝试的超时时间，仅对 DoWithContext 生效
+// 每次调用 fn 时都会基于调用方的上下文派生一个带超时的子上下文，
+// 单次尝试超时视为可重试的失败，除非调用方的上下文本身已经结束
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.AttemptTimeout = d
+		}
+	}
+}
+
+// WithAttemptTimeoutSchedule 按尝试次数设置单次尝试的超时时间，仅对 DoWithContext 生效
+// 第 n 次尝试使用 timeouts[n-1]，超出部分使用最后一项，例如 (1s, 2s, 5s) 表示第三次及之后的尝试允许 5s。
+// 超时的处理与 WithAttemptTimeout 相同，设置后优先于 WithAttemptTimeout。
+// timeouts 为空或包含不大于 0 的值时该选项被忽略
+func WithAttemptTimeoutSchedule(timeouts ...time.Duration) Option {
+	return func(o *Options) {
+		if len(timeouts) == 0 {
+			return
+		}
+		for _, d := range timeouts {
+			if d <= 0 {
+				return
+			}
+		}
+		o.AttemptTimeouts = append([]time.Duration(nil), timeouts...)
+	}
+}
+
+// WithContextPerAttempt 设置为每次尝试派生上下文的函数，仅对 DoWithContext 等传入上下文的函数生效
+// 每次调用 fn 之前以本次尝试的上下文（已包含 AttemptInfo 和 WithAttemptTimeout 的超时）和尝试次数（从 1 开始）
+// 调用 derive，fn 返回后调用其返回的 CancelFunc。derive 必须基于 parent 派生，以保证调用方的取消能够传递给 fn。
+// 与 WithAttemptTimeout 一样，派生的上下文超时而调用方的上下文未结束时，本次失败视为可重试
+func WithContextPerAttempt(derive func(parent context.Context, attempt int) (context.Context, context.CancelFunc)) Option {
+	return func(o *Options) {
+		o.ContextPerAttempt = derive
+	}
+}
+
+// WithRespectRetryAfter 设置优先使用服务端建议的重试间隔
+// 当错误为携带正数 RetryAfter 的 *HTTPError 时，该间隔会替代 Backoff 的计算结果
+func WithRespectRetryAfter() Option {
+	return func(o *Options) {
+		o.RespectRetryAfter = true
+	}
+}
+
+// WithBackoffBeforeFirst 在第一次尝试之前按重试策略第 0 次重试的间隔等待一次，可用于错开大量实例的启动时间
+// 与 WithInitialDelay 不同，等待时间由重试策略计算（例如带抖动的策略每次不同），计算时传入的错误为 nil。
+// 该等待不计为重试，不会触发 OnRetry，也不计入 Stats.TotalBackoff；两者同时设置时先执行 InitialDelay。
+// 无论是否设置，最后一次尝试失败后都不会再等待
+func WithBackoffBeforeFirst() Option {
+	return func(o *Options) {
+		o.BackoffBeforeFirst = true
+	}
+}
+
+// WithInitialDelay 设置第一次尝试之前的等待时间
+// 该等待不计为重试，不会触发 OnRetry
+func WithInitialDelay(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.InitialDelay = d
+		}
+	}
+}
+
+// WithMetrics 设置重试指标收集器
+func WithMetrics(c MetricsCollector) Option {
+	return func(o *Options) {
+		if c != nil {
+			o.Metrics = c
+		}
+	}
+}
+
+// WithContext 设置 Do 使用的上下文，使不接收上下文的函数也能在尝试前和等待期间被取消
+// DoWithContext 始终使用显式传入的上下文，忽略该选项
+func WithContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+// WithClock 设置计时与等待使用的时钟
+// 该选项主要用于测试，生产代码通常不需要设置
+func WithClock(c Clock) Option {
+	return func(o *Options) {
+		if c != nil {
+			o.Clock = c
 		}
+	}
+}
 
-		if !options.IsRetryable(err) {
-			return err
+// WithRecoverPanics 设置捕获函数中的 panic
+// 捕获到的 panic 会被转换为 *PanicError，并像普通错误一样交给 IsRetryable 判断
+func WithRecoverPanics() Option {
+	return func(o *Options) {
+		o.RecoverPanics = true
+	}
+}
+
+// WithCircuitBreaker 设置熔断器
+// 每次尝试前调用 Allow，不允许时以 ErrCircuitOpen 终止；每次尝试后记录成功或失败
+func WithCircuitBreaker(cb CircuitBreaker) Option {
+	return func(o *Options) {
+		o.CircuitBreaker = cb
+	}
+}
+
+// WithRetryAfterStrategy 设置服务端建议的间隔与本地计算的间隔的组合方式，并启用 WithRespectRetryAfter
+func WithRetryAfterStrategy(mode RetryAfterMode) Option {
+	return func(o *Options) {
+		o.RespectRetryAfter = true
+		o.RetryAfterMode = mode
+	}
+}
+
+// WithMaxRetryAfter 设置 ClampServerToMax 模式下服务端建议间隔的上限
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.MaxRetryAfter = d
 		}
+	}
+}
+
+// WithStopChannel 设置停止通道，适用于已有 done 通道的优雅退出流程
+// 每次尝试前和等待期间都会检查该通道，触发时以 ErrStopped 终止；与上下文同时设置时先触发的生效
+func WithStopChannel(stop <-chan struct{}) Option {
+	return func(o *Options) {
+		o.StopChannel = stop
+	}
+}
+
+// WithStopCondition 设置停止条件，每次尝试前调用，返回 true 时以 ErrStopConditionMet 终止重试
+// 返回的 *RetryError 同时包含最后一次尝试的错误（第一次尝试之前终止时为 nil），可以通过 errors.Is 匹配两者。
+// 条件函数通常读取由其他 goroutine 修改的共享状态，调用方需要保证其并发安全（例如使用 atomic.Bool），
+// 并且应当足够快，不要在其中阻塞。与 WithStopChannel 不同，等待重试间隔期间不会检查该条件
+func WithStopCondition(cond func() bool) Option {
+	return func(o *Options) {
+		o.StopCondition = cond
+	}
+}
+
+// WithLogger 设置结构化日志记录器
+// 每次重试以 WARN 级别记录 attempt、error 和 backoff，放弃重试时以 ERROR 级别记录；
+// 与 WithOnRetry 同时设置时两者都会被调用
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithRetryAfterExtendsAttempts 设置在服务端持续提供 Retry-After 时忽略 MaxAttempts
+// 仅在同时设置 WithRespectRetryAfter（或 WithRetryAfterStrategy）与 WithMaxElapsedTime 时生效：
+// 达到最大重试次数后，只要最后一次错误仍携带 Retry-After，就继续重试直到总耗时限制耗尽；
+// 一旦某次错误不再携带 Retry-After，则恢复按 MaxAttempts 终止。仅应用于幂等操作
+func WithRetryAfterExtendsAttempts() Option {
+	return func(o *Options) {
+		o.RetryAfterExtendsAttempts = true
+	}
+}
+
+// deadlineMargin 是截止时间感知模式下为最后一次尝试预留的最少时间
+const deadlineMargin = 10 * time.Millisecond
+
+// WithDeadlineAwareBackoff 设置根据上下文的截止时间缩短重试间隔
+// 重试间隔最多为距截止时间的剩余时间减去一个很小的余量（10ms），
+// 剩余时间不足以进行下一次尝试时直接以 ErrContextDeadlineExceeded 终止，而不是等待到超时
+func WithDeadlineAwareBackoff() Option {
+	return func(o *Options) {
+		o.DeadlineAwareBackoff = true
+	}
+}
+
+// WithIdempotent 设置操作是否幂等
+// 这是防止误重试非幂等操作（如 HTTP POST）的安全阀：设置为 false 后，
+// 第一次尝试失败且本应重试时不会再次执行函数，而是返回 ErrNonIdempotentRetry。
+// 可以配合 IsIdempotentMethod 使用，例如 WithIdempotent(IsIdempotentMethod(req.Method))
+func WithIdempotent(idempotent bool) Option {
+	return func(o *Options) {
+		o.Idempotent = idempotent
+	}
+}
+
+// WithRetryBudget 设置跨调用共享的重试预算
+// 每次执行开始时调用 Return，每次重试前调用 Allow，预算用完时以 ErrRetryBudgetExhausted 终止
+func WithRetryBudget(b RetryBudget) Option {
+	return func(o *Options) {
+		o.RetryBudget = b
+	}
+}
+
+// WithErrorMapper 设置错误转换函数，可用于脱敏或将第三方错误统一转换为 *HTTPError 等
+// 它作用于每次尝试返回的非 nil 错误，在 AfterAttempt、IsRetryable、OnRetry 和最终返回的错误之前执行。
+// 注意：转换函数对非 nil 错误返回 nil 时，本次尝试会被视为成功
+func WithErrorMapper(mapper func(err error) error) Option {
+	return func(o *Options) {
+		o.ErrorMapper = mapper
+	}
+}
+
+// WithSuccessOn 设置视为成功的错误，例如"创建时已存在"这类无害的错误，多次调用时累加
+// 函数返回的错误（经过 WithErrorMapper 转换后）通过 errors.Is 匹配其中之一时，本次尝试视为成功，
+// 重试循环返回 nil。该判断先于 IsRetryable、Unrecoverable 等其他判断，AfterAttempt、熔断器等看到的错误同样为 nil
+func WithSuccessOn(errs ...error) Option {
+	return func(o *Options) {
+		o.SuccessErrors = append(o.SuccessErrors, errs...)
+	}
+}
+
+// WithName 设置重试策略的名称
+// 名称会作为 name 属性写入日志，包含在 RetryError 的错误信息中，
+// 并在指标收集器实现了 NamedMetricsCollector 时用于获取带名称的收集器
+func WithName(name string) Option {
+	return func(o *Options) {
+		o.Name = name
+	}
+}
 
-		if attempt+1 < options.MaxAttempts {
-			options.OnRetry(attempt+1, err)
-			time.Sleep(options.Backoff(attempt))
+// WithFailureWindow 设置单次执行内的失败率限制
+// 统计最近 window 时间内各次尝试的失败率，达到 threshold 时以 ErrFailureRateExceeded 提前终止。
+// 从开始执行起经过一个完整窗口之后才会判断；DoUntil 系列函数中条件未满足不计为失败。
+// 与熔断器不同，统计只在一次 Do 调用内有效，不在调用之间共享。
+// window 不大于 0 或 threshold 不在 (0, 1] 范围内时该选项被忽略
+func WithFailureWindow(window time.Duration, threshold float64) Option {
+	return func(o *Options) {
+		if window <= 0 || threshold <= 0 || threshold > 1 {
+			return
 		}
+		o.FailureWindow = window
+		o.FailureRateThreshold = threshold
 	}
+}
+
+// Do 执行带重试的函数
+func Do(fn RetryableFunc, opts ...Option) error {
+	options := resolveOptions(opts...)
 
-	return errors.Join(ErrMaxAttemptsReached, err)
+	return runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+		return fn()
+	})
 }
 
 // DoWithContext 执行带上下文的重试函数
 func DoWithContext(ctx context.Context, fn RetryableFuncWithContext, opts ...Option) error {
-	options := defaultOptions()
-	for _, opt := range opts {
-		opt(options)
+	options := resolveOptions(opts...)
+
+	return run(ctx, options, func(ctx context.Context, attempt int) error {
+		return fn(ctx)
+	})
+}
+
+// DoForever 不限次数地执行带上下文的重试函数，直到成功、遇到不可重试的错误或上下文结束
+// 选项中的 MaxAttempts 会被忽略。ctx 不可取消且没有设置 WithMaxElapsedTime、WithMaxBackoffBudget、
+// WithStopChannel 或 WithStopCondition 时，为避免真正的无限循环，直接返回 ErrUnboundedRetry
+func DoForever(ctx context.Context, fn RetryableFuncWithContext, opts ...Option) error {
+	options := resolveOptions(opts...)
+	options.MaxAttempts = 0
+	options.MaxAttemptsFunc = nil
+
+	return run(ctx, options, func(ctx context.Context, attempt int) error {
+		return fn(ctx)
+	})
+}
+
+// DoWithAttempt 执行带重试的函数，并将当前的尝试次数（从 1 开始）传给函数
+func DoWithAttempt(fn func(attempt int) error, opts ...Option) error {
+	options := resolveOptions(opts...)
+
+	return runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+		return fn(attempt)
+	})
+}
+
+// DoWithAttemptContext 执行带上下文的重试函数，并将当前的尝试次数（从 1 开始）传给函数
+func DoWithAttemptContext(ctx context.Context, fn func(ctx context.Context, attempt int) error, opts ...Option) error {
+	options := resolveOptions(opts...)
+
+	return run(ctx, options, fn)
+}
+
+// context 返回不接收上下文的函数所使用的上下文
+func (o *Options) context() context.Context {
+	if o.Context != nil {
+		return o.Context
 	}
+	return context.Background()
+}
 
-	var err error
-	for attempt := 0; attempt < options.MaxAttempts; attempt++ {
-		select {
-		case <-ctx.Done():
-			switch ctx.Err() {
-			case context.Canceled:
-				return errors.Join(ErrContextCanceled, err)
-			case context.DeadlineExceeded:
-				return errors.Join(ErrContextDeadlineExceeded, err)
-			default:
-				return ctx.Err()
+// run 是 DoWithContext 等接收上下文的函数共用的重试循环
+func run(ctx context.Context, options *Options, fn func(ctx context.Context, attempt int) error) error {
+	_, err := runWithStats(ctx, options, fn, true)
+	return err
+}
+
+// runFunc 是 Do 等不接收上下文的函数共用的重试循环
+// fn 收到的是 ctx 本身，不会为每次尝试派生上下文，因此单次尝试的超时等选项不生效
+func runFunc(ctx context.Context, options *Options, fn func(ctx context.Context, attempt int) error) error {
+	_, err := runWithStats(ctx, options, fn, false)
+	return err
+}
+
+// runWithStats 执行重试循环，并返回本次执行的统计信息
+// withContext 表示 fn 使用其收到的上下文，只有此时才会为每次尝试派生携带 AttemptInfo 和超时的上下文
+func runWithStats(ctx context.Context, options *Options, fn func(ctx context.Context, attempt int) error, withContext bool) (stats Stats, err error) {
+	// records 是 OnComplete 使用的尝试记录
+	var records []AttemptRecord
+
+	defer func() {
+		stats.Reason = terminationReason(stats, err)
+		if retryErr, ok := err.(*RetryError); ok {
+			retryErr.Name = options.Name
+			retryErr.setContextCause(ctx)
+		}
+		if options.OnComplete != nil {
+			options.OnComplete(RunSummary{Attempts: records, Outcome: stats.Reason})
+		}
+	}()
+
+	if options.MaxAttemptsFunc != nil || options.MaxAttemptsJitter > 0 {
+		options = options.withDynamicAttempts()
+	}
+
+	if options.Name != "" {
+		options = options.named()
+	}
+
+	if options.MaxAttempts == 0 && ctx.Done() == nil && options.StopChannel == nil && options.StopCondition == nil &&
+		options.MaxElapsedTime == 0 && options.MaxBackoffBudget == 0 {
+		return stats, ErrUnboundedRetry
+	}
+
+	if options.RecoverPanics {
+		fn = recoverPanics(fn)
+	}
+
+	if options.RetryBudget != nil {
+		options.RetryBudget.Return()
+	}
+
+	// 快速路径：只执行一次且不需要等待时，直接执行一次尝试，不读取开始时间，也不准备等待和滑动窗口的状态
+	if options.singleAttempt() {
+		if stop := options.preAttempt(ctx, nil, 0); stop != nil {
+			return stats, stop
+		}
+
+		var timedOut bool
+		err, timedOut = options.attempt(ctx, fn, 1, 0, withContext, &stats, &records)
+		if result, done := options.settle(ctx, &stats, err, timedOut); done {
+			return stats, result
+		}
+		return stats, options.giveUp(ErrMaxAttemptsReached, err, stats.Attempts)
+	}
+
+	start := options.Clock.Now()
+	sleeper := &sleeper{clock: options.Clock, stopCh: options.StopChannel}
+	defer sleeper.stop()
+
+	// prevBackoff 是上一次实际等待的间隔，供有状态的重试策略使用
+	var prevBackoff time.Duration
+
+	var failures *failureWindow
+	if options.FailureWindow > 0 {
+		failures = newFailureWindow(options.FailureWindow, options.FailureRateThreshold, start)
+	}
+
+	if options.InitialDelay > 0 {
+		if cause := sleeper.sleep(ctx, options.InitialDelay); cause != nil {
+			return stats, newRetryError(cause, nil, 0)
+		}
+	}
+
+	if options.BackoffBeforeFirst {
+		if cause := sleeper.sleep(ctx, options.localBackoff(0, nil, 0)); cause != nil {
+			return stats, newRetryError(cause, nil, 0)
+		}
+	}
+
+	// 循环的终止由每次尝试之后的检查决定，MaxAttempts 大于 0 时至少执行一次
+	for attempt := 0; ; attempt++ {
+		if stop := options.preAttempt(ctx, err, stats.Attempts); stop != nil {
+			return stats, stop
+		}
+
+		var timedOut bool
+		err, timedOut = options.attempt(ctx, fn, attempt+1, options.Clock.Now().Sub(start), withContext, &stats, &records)
+
+		if failures != nil {
+			failures.record(options.Clock.Now(), err)
+		}
+
+		if result, done := options.settle(ctx, &stats, err, timedOut); done {
+			return stats, result
+		}
+
+		if failures != nil && failures.exceeded(options.Clock.Now()) {
+			return stats, newRetryError(ErrFailureRateExceeded, err, stats.Attempts)
+		}
+
+		if !options.canRetry(stats.Attempts, err) {
+			break
+		}
+
+		if !options.Idempotent {
+			return stats, newRetryError(ErrNonIdempotentRetry, err, stats.Attempts)
+		}
+
+		backoffDuration := options.nextBackoff(attempt, err, prevBackoff)
+		if options.DeadlineAwareBackoff {
+			if deadline, ok := ctx.Deadline(); ok {
+				// ctx 的截止时间基于真实时间，不能用可替换的 Clock 计算剩余时间
+				remaining := time.Until(deadline) - deadlineMargin
+				if remaining <= 0 {
+					return stats, newRetryError(ErrContextDeadlineExceeded, err, stats.Attempts)
+				}
+				if backoffDuration > remaining {
+					backoffDuration = remaining
+				}
 			}
-		default:
-			err = fn(ctx)
-			if err == nil {
-				return nil
+		}
+		// 以减法比较，避免服务端建议的极大间隔与已耗时间相加后溢出
+		if options.MaxElapsedTime > 0 && backoffDuration > options.MaxElapsedTime-options.Clock.Now().Sub(start) {
+			return stats, options.giveUp(ErrMaxElapsedTimeExceeded, err, stats.Attempts)
+		}
+		if options.MaxBackoffBudget > 0 && backoffDuration > options.MaxBackoffBudget-stats.TotalBackoff {
+			return stats, options.giveUp(ErrBackoffBudgetExhausted, err, stats.Attempts)
+		}
+
+		if options.RetryBudget != nil && !options.RetryBudget.Allow() {
+			return stats, newRetryError(ErrRetryBudgetExhausted, err, stats.Attempts)
+		}
+
+		if options.BeforeRetryCleanup != nil {
+			if cleanupErr := options.BeforeRetryCleanup(stats.Attempts, err); cleanupErr != nil {
+				return stats, errors.Join(cleanupErr, err)
 			}
+		}
 
-			if !options.IsRetryable(err) {
-				return err
+		if conditionNotMet(err) {
+			// 轮询尚未完成不是错误，不调用 OnRetry，只输出调试日志
+			if options.Logger != nil {
+				options.Logger.Debug("condition not met, polling again",
+					slog.Int("attempt", stats.Attempts),
+					slog.Duration("backoff", backoffDuration),
+				)
 			}
+		} else {
+			options.OnRetry(stats.Attempts, err)
+			if options.Logger != nil {
+				options.Logger.Warn("retrying after error",
+					slog.Int("attempt", stats.Attempts),
+					slog.Any("error", err),
+					slog.Duration("backoff", backoffDuration),
+				)
+			}
+		}
+		options.Metrics.ObserveBackoff(backoffDuration)
+		options.OnBackoff(stats.Attempts, backoffDuration)
+		if options.OnComplete != nil {
+			records[len(records)-1].Backoff = backoffDuration
+		}
 
-			if attempt+1 < options.MaxAttempts {
-				options.OnRetry(attempt+1, err)
-
-				backoffDuration := options.Backoff(attempt)
-				timer := time.NewTimer(backoffDuration)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					switch ctx.Err() {
-					case context.Canceled:
-						return errors.Join(ErrContextCanceled, err)
-					case context.DeadlineExceeded:
-						return errors.Join(ErrContextDeadlineExceeded, err)
-					default:
-						return ctx.Err()
-					}
-				case <-timer.C:
-					// 继续下一次重试
-				}
+		// 间隔为 0 时不创建定时器，上下文和停止通道在下一次尝试前检查
+		if backoffDuration > 0 {
+			if cause := sleeper.sleep(ctx, backoffDuration); cause != nil {
+				return stats, newRetryError(cause, err, stats.Attempts)
 			}
 		}
+		stats.TotalBackoff += backoffDuration
+		prevBackoff = backoffDuration
+	}
+
+	return stats, options.giveUp(ErrMaxAttemptsReached, err, stats.Attempts)
+}
+
+// singleAttempt 判断是否只会执行一次且不需要任何等待，此时 runWithStats 走快速路径
+func (o *Options) singleAttempt() bool {
+	return o.MaxAttempts == 1 && !o.RetryAfterExtendsAttempts && o.InitialDelay == 0 &&
+		!o.BackoffBeforeFirst && o.FailureWindow == 0
+}
+
+// preAttempt 在每次尝试之前检查上下文、停止通道、停止条件和熔断器，需要终止时返回重试错误
+func (o *Options) preAttempt(ctx context.Context, lastErr error, attempts int) error {
+	if cause := contextCause(ctx); cause != nil {
+		return newRetryError(cause, lastErr, attempts)
+	}
+
+	if stopped(o.StopChannel) {
+		return newRetryError(ErrStopped, lastErr, attempts)
+	}
+
+	if o.StopCondition != nil && o.StopCondition() {
+		return newRetryError(ErrStopConditionMet, lastErr, attempts)
+	}
+
+	if o.CircuitBreaker != nil && !o.CircuitBreaker.Allow() {
+		return newRetryError(ErrCircuitOpen, lastErr, attempts)
+	}
+
+	return nil
+}
+
+// attempt 执行第 attempt 次尝试（从 1 开始），更新统计信息和尝试记录，并将结果报告给回调、熔断器和有状态的重试策略
+// 返回经过 ErrorMapper 和 SuccessErrors 处理后的错误，以及本次尝试是否因单次尝试超时而失败
+func (o *Options) attempt(ctx context.Context, fn func(ctx context.Context, attempt int) error, attempt int, elapsed time.Duration,
+	withContext bool, stats *Stats, records *[]AttemptRecord) (err error, timedOut bool) {
+	o.Metrics.IncAttempt()
+	attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+	if withContext {
+		attemptCtx, cancel = o.attemptContext(ctx, attempt, elapsed)
+	}
+	o.BeforeAttempt(attempt)
+	var attemptStart time.Time
+	if o.OnComplete != nil {
+		attemptStart = o.Clock.Now()
+	}
+	err = fn(attemptCtx, attempt)
+	var attemptDuration time.Duration
+	if o.OnComplete != nil {
+		attemptDuration = o.Clock.Now().Sub(attemptStart)
+	}
+	// 单次尝试超时且父上下文未结束时，始终视为可重试；fn 不接收上下文时不会发生
+	timedOut = withContext && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	cancel()
+	stats.Attempts++
+	if isNilPermanent(err) {
+		err = nil
+	}
+	if err != nil && o.ErrorMapper != nil {
+		err = o.ErrorMapper(err)
+	}
+	if err != nil && o.isSuccess(err) {
+		err = nil
+	}
+	if o.OnComplete != nil {
+		*records = append(*records, AttemptRecord{Index: stats.Attempts, Err: err, Duration: attemptDuration})
+	}
+	o.AfterAttempt(stats.Attempts, err)
+
+	// 条件尚未满足说明依赖正常响应，不计为成功或失败
+	if o.CircuitBreaker != nil && !conditionNotMet(err) {
+		if err == nil {
+			o.CircuitBreaker.RecordSuccess()
+		} else {
+			o.CircuitBreaker.RecordFailure()
+		}
+	}
+
+	if feedback, ok := o.StatefulBackoff.(BackoffFeedback); ok && !conditionNotMet(err) {
+		if err == nil {
+			feedback.RecordSuccess()
+		} else {
+			feedback.RecordFailure()
+		}
 	}
 
-	return errors.Join(ErrMaxAttemptsReached, err)
+	return err, timedOut
+}
+
+// settle 处理一次尝试的结果，成功或遇到不可重试的错误时返回最终结果和 true，需要继续判断是否重试时返回 false
+func (o *Options) settle(ctx context.Context, stats *Stats, err error, timedOut bool) (error, bool) {
+	if err == nil {
+		stats.Succeeded = true
+		o.Metrics.IncSuccess(stats.Attempts)
+		o.OnSuccess(stats.Attempts)
+		return nil, true
+	}
+
+	if inner := unrecoverableCause(err); inner != nil {
+		return inner, true
+	}
+
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		return permanent.Err, true
+	}
+
+	if !timedOut && !o.IsRetryable(err) {
+		// fn 在父上下文结束后返回的错误（通常是 ctx.Err()）不可重试，仍按上下文结束处理
+		if cause := contextCause(ctx); cause != nil {
+			return newRetryError(cause, err, stats.Attempts), true
+		}
+		return err, true
+	}
+
+	return nil, false
+}
+
+// giveUp 在重试次数、时间或预算耗尽时触发相应的回调，并返回重试错误
+func (o *Options) giveUp(cause error, lastErr error, attempts int) error {
+	retryErr := newRetryError(cause, lastErr, attempts)
+	retryErr.Name = o.Name
+	o.Metrics.IncExhausted(attempts)
+	o.OnGiveUp(attempts, retryErr)
+	if o.Logger != nil {
+		o.Logger.Error("giving up retrying",
+			slog.Int("attempts", attempts),
+			slog.Any("error", lastErr),
+			slog.String("cause", cause.Error()),
+		)
+	}
+	return retryErr
+}
+
+// recoverPanics 包装函数，将其中的 panic 转换为 *PanicError
+func recoverPanics(fn func(ctx context.Context, attempt int) error) func(ctx context.Context, attempt int) error {
+	return func(ctx context.Context, attempt int) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		return fn(ctx, attempt)
+	}
+}
+
+// attemptContext 返回第 attempt 次尝试（从 1 开始）使用的上下文，其中包含 AttemptInfo、单次尝试的超时
+// 以及 ContextPerAttempt 派生的上下文，返回的 CancelFunc 需要在尝试结束后调用
+func (o *Options) attemptContext(ctx context.Context, attempt int, elapsed time.Duration) (context.Context, context.CancelFunc) {
+	attemptCtx, cancel := withAttemptInfo(ctx, o.attemptInfo(attempt, elapsed)), context.CancelFunc(func() {})
+	if timeout := o.attemptTimeout(attempt); timeout > 0 {
+		attemptCtx, cancel = context.WithTimeout(attemptCtx, timeout)
+	}
+	if o.ContextPerAttempt != nil {
+		attemptCtx, cancel = o.deriveAttemptContext(attemptCtx, attempt, cancel)
+	}
+	return attemptCtx, cancel
+}
+
+// attemptInfo 返回第 attempt 次尝试（从 1 开始）的元信息
+func (o *Options) attemptInfo(attempt int, elapsed time.Duration) AttemptInfo {
+	info := AttemptInfo{
+		Attempt:           attempt,
+		ElapsedSinceStart: elapsed,
+		Remaining:         -1,
+	}
+	if o.MaxAttempts > 0 {
+		// 服务端持续提供 Retry-After 时尝试次数可能超过 MaxAttempts（参见 WithRetryAfterExtendsAttempts）
+		info.Remaining = max(o.MaxAttempts-attempt, 0)
+		info.IsLast = info.Remaining == 0
+	}
+	return info
+}
+
+// attemptTimeout 返回第 attempt 次尝试（从 1 开始）的超时时间，为 0 表示不限制
+func (o *Options) attemptTimeout(attempt int) time.Duration {
+	if n := len(o.AttemptTimeouts); n > 0 {
+		return o.AttemptTimeouts[min(attempt, n)-1]
+	}
+	return o.AttemptTimeout
+}
+
+// deriveAttemptContext 使用 ContextPerAttempt 派生本次尝试的上下文，返回的 CancelFunc 同时释放 parent
+func (o *Options) deriveAttemptContext(parent context.Context, attempt int, cancelParent context.CancelFunc) (context.Context, context.CancelFunc) {
+	ctx, cancel := o.ContextPerAttempt(parent, attempt)
+	return ctx, func() {
+		if cancel != nil {
+			cancel()
+		}
+		cancelParent()
+	}
+}
+
+// withDynamicAttempts 返回应用 MaxAttemptsFunc 和 MaxAttemptsJitter 之后的选项副本
+func (o *Options) withDynamicAttempts() *Options {
+	resolved := *o
+	resolved.MaxAttemptsFunc = nil
+	resolved.MaxAttemptsJitter = 0
+
+	if o.MaxAttemptsFunc != nil {
+		if attempts := o.MaxAttemptsFunc(); attempts >= 0 {
+			resolved.MaxAttempts = attempts
+		}
+	}
+
+	if spread := o.MaxAttemptsJitter; spread > 0 && resolved.MaxAttempts > 0 {
+		resolved.MaxAttempts += packageRand().Intn(2*spread+1) - spread
+		if resolved.MaxAttempts < 1 {
+			resolved.MaxAttempts = 1
+		}
+	}
+	return &resolved
+}
+
+// named 返回将名称应用到日志记录器和指标收集器之后的选项副本
+func (o *Options) named() *Options {
+	named := *o
+	if named.Logger != nil {
+		named.Logger = named.Logger.With(slog.String("name", o.Name))
+	}
+	if c, ok := named.Metrics.(NamedMetricsCollector); ok {
+		named.Metrics = c.WithName(o.Name)
+	}
+	return &named
+}
+
+// isSuccess 判断错误是否匹配 SuccessErrors 之一
+func (o *Options) isSuccess(err error) bool {
+	for _, target := range o.SuccessErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// canRetry 判断已执行 attempts 次、最后一次错误为 err 时是否还能继续重试
+func (o *Options) canRetry(attempts int, err error) bool {
+	if !o.attemptsExhausted(attempts) {
+		return true
+	}
+
+	if o.RetryAfterExtendsAttempts && o.RespectRetryAfter && o.MaxElapsedTime > 0 {
+		_, ok := retryAfter(err)
+		return ok
+	}
+	return false
+}
+
+// attemptsExhausted 判断已执行 attempts 次后是否达到最大重试次数
+func (o *Options) attemptsExhausted(attempts int) bool {
+	return o.MaxAttempts > 0 && attempts >= o.MaxAttempts
+}
+
+// nextBackoff 计算第 attempt 次失败后的重试间隔
+func (o *Options) nextBackoff(attempt int, err error, prev time.Duration) time.Duration {
+	if o.RespectRetryAfter {
+		if server, ok := retryAfter(err); ok {
+			return o.RetryAfterMode.combine(server, func() time.Duration {
+				return o.multiplyBackoff(o.localBackoff(attempt, err, prev), err)
+			}, o.MaxRetryAfter)
+		}
+	}
+
+	return o.multiplyBackoff(o.localBackoff(attempt, err, prev), err)
+}
+
+// multiplyBackoff 将间隔乘以所有与 err 匹配的倍数
+func (o *Options) multiplyBackoff(d time.Duration, err error) time.Duration {
+	if len(o.BackoffMultipliers) == 0 || err == nil {
+		return d
+	}
+
+	factor := 1.0
+	for _, m := range o.BackoffMultipliers {
+		if m.Match(err) {
+			factor *= m.Multiplier
+		}
+	}
+	if factor == 1 {
+		return d
+	}
+
+	// 以 float64 计算并截断，避免溢出
+	scaled := float64(d) * factor
+	if scaled > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(scaled)
+}
+
+// localBackoff 返回本地重试策略计算出的间隔
+// 自定义策略或溢出产生的负数间隔按 0 处理，OnBackoff 等回调看到的也是 0
+func (o *Options) localBackoff(attempt int, err error, prev time.Duration) time.Duration {
+	var d time.Duration
+	switch {
+	case o.BackoffWithError != nil:
+		d = o.BackoffWithError(attempt, err)
+	case o.StatefulBackoff != nil:
+		d = o.StatefulBackoff.Next(attempt, prev)
+	default:
+		d = o.Backoff(attempt)
+	}
+
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// sleeper 负责重试间隔的等待，在一次重试循环内复用同一个定时器
+type sleeper struct {
+	clock  Clock
+	timer  Timer
+	stopCh <-chan struct{}
+}
+
+// sleep 等待重试间隔，等待期间上下文结束或停止通道触发时返回对应的哨兵错误
+func (s *sleeper) sleep(ctx context.Context, d time.Duration) error {
+	// 没有可以中断等待的信号时直接休眠
+	if ctx.Done() == nil && s.stopCh == nil {
+		s.clock.Sleep(d)
+		return nil
+	}
+
+	if s.timer == nil {
+		s.timer = s.clock.NewTimer(d)
+	} else {
+		// 定时器只会在触发并被读取后复用，此时通道为空，可以直接重置
+		s.timer.Reset(d)
+	}
+
+	select {
+	case <-ctx.Done():
+		s.drain()
+		return contextCause(ctx)
+	case <-s.stopCh:
+		s.drain()
+		return ErrStopped
+	case <-s.timer.C():
+		// 继续下一次重试
+		return nil
+	}
+}
+
+// drain 停止定时器并清空其通道
+func (s *sleeper) drain() {
+	if !s.timer.Stop() {
+		select {
+		case <-s.timer.C():
+		default:
+		}
+	}
+}
+
+// stop 释放定时器
+func (s *sleeper) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// stopped 判断停止通道是否已经触发
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// contextCause 在上下文结束时返回对应的哨兵错误，否则返回 nil
+func contextCause(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.Canceled:
+		return ErrContextCanceled
+	case context.DeadlineExceeded:
+		return ErrContextDeadlineExceeded
+	default:
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,789 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttemptTimeoutIgnoredWithoutContext(t *testing.T) {
+	errBadInput := errors.New("bad input")
+	calls := 0
+	err := Do(func() error {
+		calls++
+		time.Sleep(20 * time.Millisecond)
+		return errBadInput
+	}, WithAttemptTimeout(5*time.Millisecond), WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, errBadInput) {
+		t.Fatalf("err = %v, want %v", err, errBadInput)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
+
+func TestAttemptTimeoutRetriesWithContext(t *testing.T) {
+	calls := 0
+	err := DoWithContext(context.Background(), func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return errors.New("bad input")
+	}, WithAttemptTimeout(5*time.Millisecond), WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want %v", err, ErrMaxAttemptsReached)
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 3", calls)
+	}
+}
+
+func TestDefaultDoesNotRetryContextCanceled(t *testing.T) {
+	calls := 0
+	err := Do(func() error {
+		calls++
+		return context.Canceled
+	}, WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryAllErrorsRetriesNonTransient(t *testing.T) {
+	calls := 0
+	err := Do(func() error {
+		calls++
+		return errors.New("invalid argument")
+	}, WithRetryAllErrors(), WithMaxAttempts(3), WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestContextErrorFromFnReportsContextCause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stats, err := DoWithStatsContext(ctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	}, WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, ErrContextCanceled) {
+		t.Fatalf("err = %v, want ErrContextCanceled", err)
+	}
+	if stats.Reason != ContextCanceled {
+		t.Errorf("Reason = %v, want %v", stats.Reason, ContextCanceled)
+	}
+}
+
+func TestOnGiveUpReportsReason(t *testing.T) {
+	errTransient := errors.New("transient")
+	tests := []struct {
+		name string
+		opts []Option
+		want TerminationReason
+	}{
+		{"max attempts", []Option{WithMaxAttempts(2), WithBackoff(ConstantBackoff(0))}, MaxAttempts},
+		{"max elapsed time", []Option{WithMaxAttempts(5), WithBackoff(ConstantBackoff(time.Hour)), WithMaxElapsedTime(time.Second)}, MaxElapsedTime},
+		{"backoff budget", []Option{WithMaxAttempts(5), WithBackoff(ConstantBackoff(time.Hour)), WithMaxBackoffBudget(time.Second)}, BackoffBudgetExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *RetryError
+			var gotAttempts int
+			opts := append([]Option{WithRetryAllErrors(), WithOnGiveUp(func(attempts int, err error) {
+				gotAttempts = attempts
+				errors.As(err, &got)
+			})}, tt.opts...)
+			err := Do(func() error { return errTransient }, opts...)
+
+			if got == nil {
+				t.Fatal("OnGiveUp not called with a *RetryError")
+			}
+			if gotAttempts != got.Attempts {
+				t.Errorf("attempts = %d, want %d", gotAttempts, got.Attempts)
+			}
+			if got.Reason != tt.want {
+				t.Errorf("Reason = %v, want %v", got.Reason, tt.want)
+			}
+			if got.LastErr != errTransient {
+				t.Errorf("LastErr = %v, want %v", got.LastErr, errTransient)
+			}
+			if err != error(got) {
+				t.Errorf("returned error %v differs from OnGiveUp argument %v", err, got)
+			}
+		})
+	}
+}
+
+func TestOnGiveUpNotCalledForNonRetryable(t *testing.T) {
+	called := false
+	_ = Do(func() error {
+		return errors.New("bad input")
+	}, WithOnGiveUp(func(attempts int, err error) { called = true }))
+
+	if called {
+		t.Error("OnGiveUp called for a non-retryable error")
+	}
+}
+
+func TestMaxBackoffBudgetStopsFastFailingLoop(t *testing.T) {
+	calls := 0
+	stats, err := DoWithStats(func() error {
+		calls++
+		return errors.New("transient")
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(1000),
+		WithBackoff(ConstantBackoff(10*time.Millisecond)),
+		WithMaxBackoffBudget(35*time.Millisecond),
+	)
+
+	if !errors.Is(err, ErrBackoffBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrBackoffBudgetExhausted", err)
+	}
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+	if stats.TotalBackoff != 30*time.Millisecond {
+		t.Errorf("TotalBackoff = %v, want 30ms", stats.TotalBackoff)
+	}
+}
+
+// timerClock 记录创建的定时器，用于检查定时器的复用和释放
+type timerClock struct {
+	realClock
+	timers []*time.Timer
+}
+
+func (c *timerClock) NewTimer(d time.Duration) Timer {
+	t := time.NewTimer(d)
+	c.timers = append(c.timers, t)
+	return realTimer{t: t}
+}
+
+func TestBackoffReusesTimer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &timerClock{}
+
+	err := DoWithContext(ctx, func(ctx context.Context) error {
+		return errors.New("transient")
+	}, WithRetryAllErrors(), WithMaxAttempts(5), WithBackoff(ConstantBackoff(time.Millisecond)), WithClock(clock))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	if len(clock.timers) != 1 {
+		t.Errorf("created %d timers, want 1", len(clock.timers))
+	}
+}
+
+func TestBackoffTimerStoppedOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &timerClock{}
+
+	err := DoWithContext(ctx, func(ctx context.Context) error {
+		return errors.New("transient")
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(5),
+		WithBackoff(ConstantBackoff(time.Hour)),
+		WithClock(clock),
+		WithOnBackoff(func(attempt int, delay time.Duration) { cancel() }),
+	)
+
+	if !errors.Is(err, ErrContextCanceled) {
+		t.Fatalf("err = %v, want ErrContextCanceled", err)
+	}
+	if len(clock.timers) != 1 {
+		t.Fatalf("created %d timers, want 1", len(clock.timers))
+	}
+	for i, timer := range clock.timers {
+		// Stop 返回 true 说明定时器在重试结束后仍在运行
+		if timer.Stop() {
+			t.Errorf("timer %d still active after cancellation", i)
+		}
+	}
+}
+
+func TestRetryAfterExtendsAttempts(t *testing.T) {
+	hints := []time.Duration{40 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond, 10 * time.Millisecond}
+	stub := func(calls *int) RetryableFunc {
+		return func() error {
+			i := *calls
+			*calls++
+			if i < len(hints) {
+				return NewHTTPErrorWithRetryAfter(http.StatusTooManyRequests, "slow down", hints[i])
+			}
+			return NewHTTPError(http.StatusServiceUnavailable, "unavailable")
+		}
+	}
+
+	var calls int
+	var delays []time.Duration
+	err := Do(stub(&calls),
+		WithMaxAttempts(2),
+		WithBackoff(ConstantBackoff(0)),
+		WithRespectRetryAfter(),
+		WithMaxElapsedTime(time.Second),
+		WithRetryAfterExtendsAttempts(),
+		WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+	)
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	// 4 次携带 Retry-After 的错误之后，第 5 次错误不再携带，恢复按 MaxAttempts 终止
+	if calls != 5 {
+		t.Errorf("calls = %d, want 5", calls)
+	}
+	if !slices.Equal(delays, hints) {
+		t.Errorf("delays = %v, want %v", delays, hints)
+	}
+
+	// 没有总耗时限制时不会延长
+	calls = 0
+	_ = Do(stub(&calls),
+		WithMaxAttempts(2),
+		WithBackoff(ConstantBackoff(0)),
+		WithRespectRetryAfter(),
+		WithRetryAfterExtendsAttempts(),
+	)
+	if calls != 2 {
+		t.Errorf("without MaxElapsedTime: calls = %d, want 2", calls)
+	}
+}
+
+func TestDeadlineAwareBackoff(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	var delays []time.Duration
+	start := time.Now()
+	err := DoWithContext(ctx, func(ctx context.Context) error {
+		calls++
+		return errors.New("transient")
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(10),
+		WithBackoff(ConstantBackoff(time.Hour)),
+		WithDeadlineAwareBackoff(),
+		WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+	)
+
+	if !errors.Is(err, ErrContextDeadlineExceeded) {
+		t.Fatalf("err = %v, want ErrContextDeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("elapsed = %v, backoff was not shortened", elapsed)
+	}
+	if calls < 2 {
+		t.Errorf("calls = %d, want at least 2", calls)
+	}
+	for i, d := range delays {
+		if d > 100*time.Millisecond-deadlineMargin {
+			t.Errorf("delay %d = %v exceeds remaining time", i, d)
+		}
+	}
+}
+
+func TestDeadlineAwareBackoffIgnoresFakeClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var delays []time.Duration
+	_ = DoWithContext(ctx, func(ctx context.Context) error {
+		return errors.New("transient")
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(2),
+		WithBackoff(ConstantBackoff(time.Hour)),
+		WithClock(newFakeClock()),
+		WithDeadlineAwareBackoff(),
+		WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+	)
+
+	if len(delays) != 1 || delays[0] > time.Second-deadlineMargin {
+		t.Errorf("delays = %v, want one delay capped by the real deadline", delays)
+	}
+}
+
+func TestDeadlineAwareBackoffStopsEarly(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), deadlineMargin/2)
+	defer cancel()
+
+	calls := 0
+	err := DoWithContext(ctx, func(ctx context.Context) error {
+		calls++
+		return errors.New("transient")
+	}, WithRetryAllErrors(), WithBackoff(ConstantBackoff(time.Second)), WithDeadlineAwareBackoff())
+
+	if !errors.Is(err, ErrContextDeadlineExceeded) {
+		t.Fatalf("err = %v, want ErrContextDeadlineExceeded", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+// fakeClock 是只在 Sleep 时前进的假时钟，用于不真实等待地检查时间相关的行为
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.Sleep(d)
+	return realTimer{t: time.NewTimer(0)}
+}
+
+func TestBackoffBeforeFirstTiming(t *testing.T) {
+	backoff := func(attempt int) time.Duration { return time.Duration(attempt+1) * 10 * time.Millisecond }
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []time.Duration
+	}{
+		{"default", nil, []time.Duration{0, 10 * time.Millisecond, 30 * time.Millisecond}},
+		{"before first", []Option{WithBackoffBeforeFirst()}, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			start := clock.Now()
+			var at []time.Duration
+			opts := append([]Option{
+				WithRetryAllErrors(),
+				WithMaxAttempts(3),
+				WithBackoff(backoff),
+				WithClock(clock),
+				WithBeforeAttempt(func(attempt int) { at = append(at, clock.Now().Sub(start)) }),
+			}, tt.opts...)
+
+			_ = Do(func() error { return errBench }, opts...)
+
+			if !slices.Equal(at, tt.want) {
+				t.Errorf("attempt times = %v, want %v", at, tt.want)
+			}
+			// 最后一次尝试之后不会等待
+			if end := clock.Now().Sub(start); end != tt.want[len(tt.want)-1] {
+				t.Errorf("finished at %v, want %v", end, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
+
+func TestCancelCausePropagates(t *testing.T) {
+	errShutdown := errors.New("shutting down")
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	err := DoWithContext(ctx, func(ctx context.Context) error {
+		return errors.New("transient")
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(5),
+		WithBackoff(ConstantBackoff(time.Hour)),
+		WithOnBackoff(func(attempt int, delay time.Duration) { cancel(errShutdown) }),
+	)
+
+	if !errors.Is(err, errShutdown) {
+		t.Errorf("err = %v, want it to wrap %v", err, errShutdown)
+	}
+	if !errors.Is(err, ErrContextCanceled) {
+		t.Errorf("err = %v, want ErrContextCanceled", err)
+	}
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.ContextCause != errShutdown {
+		t.Errorf("ContextCause = %v, want %v", retryErr, errShutdown)
+	}
+}
+
+func TestMaxAttemptsFunc(t *testing.T) {
+	limits := []int{2, 4, -1, 1}
+	next := 0
+	r := New(
+		WithRetryAllErrors(),
+		WithMaxAttempts(3),
+		WithBackoff(ConstantBackoff(0)),
+		WithMaxAttemptsFunc(func() int {
+			n := limits[next]
+			next++
+			return n
+		}),
+	)
+
+	// 负数表示使用静态设置的 MaxAttempts
+	for i, want := range []int{2, 4, 3, 1} {
+		calls := 0
+		_ = r.Do(func() error {
+			calls++
+			return errBench
+		})
+		if calls != want {
+			t.Errorf("call %d: attempts = %d, want %d", i, calls, want)
+		}
+	}
+}
+
+func TestWithSuccessOnWrapped(t *testing.T) {
+	errAlreadyExists := errors.New("already exists")
+	calls, retried := 0, false
+	stats, err := DoWithStats(func() error {
+		calls++
+		return fmt.Errorf("create bucket: %w", errAlreadyExists)
+	},
+		WithSuccessOn(errAlreadyExists),
+		// 成功判断先于 IsRetryable
+		WithIsRetryable(func(err error) bool { retried = true; return true }),
+	)
+
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	if calls != 1 || !stats.Succeeded {
+		t.Errorf("calls = %d, Succeeded = %v, want 1 and true", calls, stats.Succeeded)
+	}
+	if retried {
+		t.Error("IsRetryable consulted for a success error")
+	}
+}
+
+func TestBackoffMultiplierFor(t *testing.T) {
+	isRateLimited := HTTPStatusRetryable(http.StatusTooManyRequests)
+	isUnavailable := HTTPStatusRetryable(http.StatusServiceUnavailable)
+	errs := []error{
+		NewHTTPError(http.StatusServiceUnavailable, "unavailable"),
+		NewHTTPError(http.StatusTooManyRequests, "too many requests"),
+		NewHTTPError(http.StatusServiceUnavailable, "unavailable"),
+		nil,
+	}
+
+	calls := 0
+	var delays []time.Duration
+	err := Do(func() error {
+		err := errs[calls]
+		calls++
+		return err
+	},
+		WithMaxAttempts(len(errs)),
+		WithBackoff(ExponentialBackoff(10*time.Millisecond, time.Second)),
+		WithBackoffMultiplierFor(isRateLimited, 4),
+		WithBackoffMultiplierFor(isRateLimited, 2),
+		WithBackoffMultiplierFor(isUnavailable, 0.5),
+		WithClock(newFakeClock()),
+		WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+	)
+
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	// 10ms*0.5、20ms*4*2、40ms*0.5
+	want := []time.Duration{5 * time.Millisecond, 160 * time.Millisecond, 20 * time.Millisecond}
+	if !slices.Equal(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+}
+
+func TestNegativeBackoffClampedToZero(t *testing.T) {
+	negative := func(attempt int) time.Duration { return -1 * time.Second }
+
+	for _, withContext := range []bool{false, true} {
+		calls := 0
+		var delays []time.Duration
+		opts := []Option{
+			WithRetryAllErrors(),
+			WithMaxAttempts(5),
+			WithBackoff(negative),
+			WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+		}
+		fn := func(ctx context.Context) error {
+			calls++
+			return errBench
+		}
+
+		var err error
+		if withContext {
+			ctx, cancel := context.WithCancel(context.Background())
+			err = DoWithContext(ctx, fn, opts...)
+			cancel()
+		} else {
+			err = Do(func() error { return fn(context.Background()) }, opts...)
+		}
+
+		if !errors.Is(err, ErrMaxAttemptsReached) {
+			t.Fatalf("withContext=%v: err = %v, want ErrMaxAttemptsReached", withContext, err)
+		}
+		if calls != 5 {
+			t.Errorf("withContext=%v: calls = %d, want 5", withContext, calls)
+		}
+		if want := make([]time.Duration, 4); !slices.Equal(delays, want) {
+			t.Errorf("withContext=%v: delays = %v, want %v", withContext, delays, want)
+		}
+	}
+}
+
+func TestMaxAttemptsJitter(t *testing.T) {
+	seen := map[int]bool{}
+	for i := 0; i < 500; i++ {
+		calls := 0
+		_ = Do(func() error {
+			calls++
+			return errBench
+		}, WithRetryAllErrors(), WithMaxAttempts(5), WithMaxAttemptsJitter(2), WithBackoff(ConstantBackoff(0)))
+
+		if calls < 3 || calls > 7 {
+			t.Fatalf("run %d: attempts = %d, want within [3, 7]", i, calls)
+		}
+		seen[calls] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("saw attempt counts %v, want all of 3..7", seen)
+	}
+}
+
+func TestMaxAttemptsJitterLowerBound(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		calls := 0
+		_ = Do(func() error {
+			calls++
+			return errBench
+		}, WithRetryAllErrors(), WithMaxAttempts(2), WithMaxAttemptsJitter(5), WithBackoff(ConstantBackoff(0)))
+
+		if calls < 1 || calls > 7 {
+			t.Fatalf("run %d: attempts = %d, want within [1, 7]", i, calls)
+		}
+	}
+}
+
+func TestStopConditionToggledMidLoop(t *testing.T) {
+	var stop atomic.Bool
+	calls := 0
+	err := Do(func() error {
+		calls++
+		if calls == 2 {
+			stop.Store(true)
+		}
+		return errBench
+	}, WithRetryAllErrors(), WithMaxAttempts(10), WithBackoff(ConstantBackoff(0)), WithStopCondition(stop.Load))
+
+	if !errors.Is(err, ErrStopConditionMet) || !errors.Is(err, errBench) {
+		t.Fatalf("err = %v, want ErrStopConditionMet wrapping the last error", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestStopConditionBeforeFirstAttempt(t *testing.T) {
+	calls := 0
+	err := Do(func() error {
+		calls++
+		return nil
+	}, WithStopCondition(func() bool { return true }))
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Cause != ErrStopConditionMet || retryErr.LastErr != nil {
+		t.Fatalf("err = %v, want ErrStopConditionMet without a last error", err)
+	}
+	if calls != 0 {
+		t.Errorf("calls = %d, want 0", calls)
+	}
+}
+
+func TestBeforeRetryCleanup(t *testing.T) {
+	var cleaned []int
+	calls := 0
+	err := Do(func() error {
+		calls++
+		if calls < 4 {
+			return errBench
+		}
+		return nil
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(5),
+		WithBackoff(ConstantBackoff(0)),
+		WithBeforeRetryCleanup(func(attempt int, err error) error {
+			cleaned = append(cleaned, attempt)
+			return nil
+		}),
+	)
+
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(cleaned, want) {
+		t.Errorf("cleanup attempts = %v, want %v", cleaned, want)
+	}
+}
+
+func TestBeforeRetryCleanupNotRunAfterLastAttempt(t *testing.T) {
+	cleanups := 0
+	_ = Do(func() error { return errBench },
+		WithRetryAllErrors(),
+		WithMaxAttempts(3),
+		WithBackoff(ConstantBackoff(0)),
+		WithBeforeRetryCleanup(func(attempt int, err error) error {
+			cleanups++
+			return nil
+		}),
+	)
+
+	if cleanups != 2 {
+		t.Errorf("cleanups = %d, want 2", cleanups)
+	}
+}
+
+func TestBeforeRetryCleanupFailureStopsRetries(t *testing.T) {
+	errCleanup := errors.New("remove temp file")
+	calls := 0
+	err := Do(func() error {
+		calls++
+		return errBench
+	},
+		WithRetryAllErrors(),
+		WithMaxAttempts(5),
+		WithBackoff(ConstantBackoff(0)),
+		WithBeforeRetryCleanup(func(attempt int, err error) error { return errCleanup }),
+	)
+
+	if !errors.Is(err, errCleanup) || !errors.Is(err, errBench) {
+		t.Fatalf("err = %v, want both the cleanup and attempt errors", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestAttemptTimeoutSchedule(t *testing.T) {
+	schedule := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	var budgets []time.Duration
+	err := DoWithContext(context.Background(), func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return Permanent(errors.New("attempt has no deadline"))
+		}
+		budgets = append(budgets, time.Until(deadline))
+		return errBench
+	}, WithRetryAllErrors(), WithMaxAttempts(4), WithBackoff(ConstantBackoff(0)), WithAttemptTimeoutSchedule(schedule...))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	// 最后一项会在之后的尝试中重复使用
+	want := append(schedule, schedule[len(schedule)-1])
+	for i, budget := range budgets {
+		if budget > want[i] || budget < want[i]-50*time.Millisecond {
+			t.Errorf("attempt %d: deadline in %v, want about %v", i+1, budget, want[i])
+		}
+	}
+}
+
+func TestAttemptTimeoutScheduleBoundsSlowAttempt(t *testing.T) {
+	var elapsed []time.Duration
+	_ = DoWithContext(context.Background(), func(ctx context.Context) error {
+		start := time.Now()
+		<-ctx.Done()
+		elapsed = append(elapsed, time.Since(start))
+		return ctx.Err()
+	}, WithMaxAttempts(2), WithBackoff(ConstantBackoff(0)), WithAttemptTimeoutSchedule(10*time.Millisecond, 40*time.Millisecond))
+
+	if len(elapsed) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(elapsed))
+	}
+	if elapsed[0] >= 40*time.Millisecond || elapsed[1] < 40*time.Millisecond {
+		t.Errorf("attempt durations = %v, want about [10ms 40ms]", elapsed)
+	}
+}
+
+func TestDoForeverRejectsUnboundedContext(t *testing.T) {
+	calls := 0
+	err := DoForever(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("transient")
+	}, WithRetryAllErrors())
+
+	if !errors.Is(err, ErrUnboundedRetry) {
+		t.Fatalf("err = %v, want ErrUnboundedRetry", err)
+	}
+	if calls != 0 {
+		t.Errorf("calls = %d, want 0", calls)
+	}
+}
+
+func TestDoForeverRetriesUntilSuccess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	err := DoForever(ctx, func(ctx context.Context) error {
+		calls++
+		if calls < 10 {
+			return errors.New("transient")
+		}
+		return nil
+	}, WithRetryAllErrors(), WithMaxAttempts(2), WithBackoff(ConstantBackoff(0)))
+
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	if calls != 10 {
+		t.Errorf("calls = %d, want 10", calls)
+	}
+}
+
+func TestUnlimitedAttemptsWithStopCondition(t *testing.T) {
+	calls := 0
+	err := Do(func() error {
+		calls++
+		return errors.New("transient")
+	},
+		WithRetryAllErrors(),
+		WithUnlimitedAttempts(),
+		WithBackoff(ConstantBackoff(0)),
+		WithStopCondition(func() bool { return calls >= 5 }),
+	)
+
+	if !errors.Is(err, ErrStopConditionMet) {
+		t.Fatalf("err = %v, want ErrStopConditionMet", err)
+	}
+	if calls != 5 {
+		t.Errorf("calls = %d, want 5", calls)
+	}
+}
@@ -0,0 +1,31 @@
+package retry
+
+import (
+	"context"
+)
+
+// Retryer 保存一组已解析的重试选项，可在多次调用之间复用
+type Retryer struct {
+	options *Options
+}
+
+// New 创建新的 Retryer，选项只在创建时解析一次
+func New(opts ...Option) *Retryer {
+	return &Retryer{
+		options: resolveOptions(opts...),
+	}
+}
+
+// Do 使用 Retryer 的选项执行带重试的函数
+func (r *Retryer) Do(fn RetryableFunc) error {
+	return runFunc(r.options.context(), r.options, func(ctx context.Context, attempt int) error {
+		return fn()
+	})
+}
+
+// DoWithContext 使用 Retryer 的选项执行带上下文的重试函数
+func (r *Retryer) DoWithContext(ctx context.Context, fn RetryableFuncWithContext) error {
+	return run(ctx, r.options, func(ctx context.Context, attempt int) error {
+		return fn(ctx)
+	})
+}
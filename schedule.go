@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"time"
+)
+
+// Schedule 返回在给定选项下、每次尝试都失败时重试循环依次等待的间隔，不会执行任何函数，也不会休眠
+// attempts 为模拟的尝试次数，最后一次尝试之后不等待，因此结果最多包含 attempts-1 个间隔；
+// 选项中的 MaxAttempts、MaxElapsedTime（假设函数执行不耗时）和 MaxBackoffBudget 会提前结束模拟。
+// 计算间隔时传入的错误为 nil，因此不包含 Retry-After，也不包含 InitialDelay 和 WithBackoffBeforeFirst 的等待。
+// 带抖动的策略可以使用 ExponentialBackoffWithJitterRand 传入固定种子的随机数生成器，
+// 或者先调用 SeedJitter 固定包级随机数生成器，以得到确定的结果。
+// 有状态的重试策略会像真实执行一样被推进
+func Schedule(attempts int, opts ...Option) []time.Duration {
+	options := resolveOptions(opts...)
+	if options.MaxAttemptsFunc != nil || options.MaxAttemptsJitter > 0 {
+		options = options.withDynamicAttempts()
+	}
+	if options.MaxAttempts > 0 && options.MaxAttempts < attempts {
+		attempts = options.MaxAttempts
+	}
+	if attempts <= 1 {
+		return nil
+	}
+
+	delays := make([]time.Duration, 0, attempts-1)
+	var total, prev time.Duration
+	for attempt := 0; attempt < attempts-1; attempt++ {
+		d := options.nextBackoff(attempt, nil, prev)
+		if options.MaxElapsedTime > 0 && total+d > options.MaxElapsedTime {
+			break
+		}
+		if options.MaxBackoffBudget > 0 && total+d > options.MaxBackoffBudget {
+			break
+		}
+
+		delays = append(delays, d)
+		total += d
+		prev = d
+	}
+
+	return delays
+}
@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+)
+
+// 默认可重试的 SQLSTATE
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateConnectionException  = "08"
+)
+
+// defaultSQLRetryer 是 IsRetryableSQLError 使用的判断器
+var defaultSQLRetryer = NewSQLRetryer(
+	sqlStateSerializationFailure,
+	sqlStateDeadlockDetected,
+	sqlStateConnectionException,
+)
+
+// IsRetryableSQLError 判断 database/sql 返回的错误是否可重试
+// driver.ErrBadConn、超时和网络错误可重试；驱动错误实现了 SQLState() string 方法时，
+// 序列化失败（40001）、死锁（40P01）和连接异常（08 类）可重试，其余错误（如违反约束）不可重试。
+// 需要重试其他 SQLSTATE 时使用 NewSQLRetryer
+func IsRetryableSQLError(err error) bool {
+	return defaultSQLRetryer.IsRetryable(err)
+}
+
+// SQLRetryer 按 SQLSTATE 判断数据库错误是否可重试，可并发使用
+// SQLSTATE 通过错误链中实现了 SQLState() string 方法的错误获取（例如 pgx 和 lib/pq 的错误类型），
+// 其他驱动可以先用 WithErrorMapper 将错误包装为实现该方法的类型
+type SQLRetryer struct {
+	mu    sync.RWMutex
+	codes map[string]struct{}
+}
+
+// NewSQLRetryer 创建新的 SQLSTATE 判断器，codes 为可重试的 SQLSTATE
+// 长度为 2 的代码表示整个类别，例如 "08" 匹配所有连接异常
+func NewSQLRetryer(codes ...string) *SQLRetryer {
+	r := &SQLRetryer{codes: make(map[string]struct{}, len(codes))}
+	r.Register(codes...)
+	return r
+}
+
+// Register 增加可重试的 SQLSTATE
+func (r *SQLRetryer) Register(codes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range codes {
+		r.codes[code] = struct{}{}
+	}
+}
+
+// IsRetryable 判断错误是否可重试，可直接传给 WithIsRetryable
+// 除了已注册的 SQLSTATE，driver.ErrBadConn、超时和网络错误也总是可重试
+func (r *SQLRetryer) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return r.retryableState(stateErr.SQLState())
+	}
+
+	return IsNetworkError(err)
+}
+
+// retryableState 判断 SQLSTATE 或其类别是否已注册
+func (r *SQLRetryer) retryableState(state string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.codes[state]; ok {
+		return true
+	}
+	if len(state) > 2 {
+		_, ok := r.codes[state[:2]]
+		return ok
+	}
+	return false
+}
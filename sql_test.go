@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+// sqlStateError 模拟实现了 SQLState() 方法的驱动错误
+type sqlStateError struct {
+	state string
+}
+
+func (e *sqlStateError) Error() string { return "sql error " + e.state }
+
+func (e *sqlStateError) SQLState() string { return e.state }
+
+func TestIsRetryableSQLError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bad conn", driver.ErrBadConn, true},
+		{"wrapped bad conn", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"connection reset", syscall.ECONNRESET, true},
+		{"serialization failure", &sqlStateError{"40001"}, true},
+		{"deadlock", fmt.Errorf("tx: %w", &sqlStateError{"40P01"}), true},
+		{"connection class", &sqlStateError{"08006"}, true},
+		{"unique violation", &sqlStateError{"23505"}, false},
+		{"plain", errors.New("syntax error"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableSQLError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableSQLError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLRetryerRegister(t *testing.T) {
+	r := NewSQLRetryer("40001")
+	lockTimeout := &sqlStateError{"55P03"}
+
+	if r.IsRetryable(lockTimeout) {
+		t.Error("unregistered SQLSTATE should not be retryable")
+	}
+	r.Register("55P03")
+	if !r.IsRetryable(lockTimeout) {
+		t.Error("registered SQLSTATE should be retryable")
+	}
+	if r.IsRetryable(&sqlStateError{"08006"}) {
+		t.Error("connection class is not registered on a custom retryer")
+	}
+}
@@ -0,0 +1,165 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// TerminationReason 重试循环终止的原因
+// 零值表示循环未开始执行（例如选项不合法）
+type TerminationReason int
+
+const (
+	// Succeeded 函数执行成功
+	Succeeded TerminationReason = iota + 1
+	// MaxAttempts 达到最大重试次数
+	MaxAttempts
+	// NonRetryable 遇到不可重试的错误
+	NonRetryable
+	// ContextCanceled 上下文被取消
+	ContextCanceled
+	// ContextDeadline 上下文超时
+	ContextDeadline
+	// CircuitOpen 熔断器处于打开状态
+	CircuitOpen
+	// MaxElapsedTime 超出总耗时限制
+	MaxElapsedTime
+	// BackoffBudgetExhausted 重试等待的总时间超出预算
+	BackoffBudgetExhausted
+	// Stopped 停止通道被触发
+	Stopped
+	// NonIdempotent 需要重试但操作被标记为非幂等
+	NonIdempotent
+	// RetryBudgetExhausted 共享的重试预算已用完
+	RetryBudgetExhausted
+	// FailureRateExceeded 滑动窗口内的失败率达到阈值
+	FailureRateExceeded
+	// StopConditionMet 停止条件已满足
+	StopConditionMet
+)
+
+// String 返回终止原因的名称
+func (r TerminationReason) String() string {
+	switch r {
+	case Succeeded:
+		return "succeeded"
+	case MaxAttempts:
+		return "max attempts"
+	case NonRetryable:
+		return "non-retryable"
+	case ContextCanceled:
+		return "context canceled"
+	case ContextDeadline:
+		return "context deadline"
+	case CircuitOpen:
+		return "circuit open"
+	case MaxElapsedTime:
+		return "max elapsed time"
+	case BackoffBudgetExhausted:
+		return "backoff budget exhausted"
+	case Stopped:
+		return "stopped"
+	case NonIdempotent:
+		return "non-idempotent"
+	case RetryBudgetExhausted:
+		return "retry budget exhausted"
+	case FailureRateExceeded:
+		return "failure rate exceeded"
+	case StopConditionMet:
+		return "stop condition met"
+	default:
+		return "unknown"
+	}
+}
+
+// reasonForCause 返回哨兵错误对应的终止原因
+func reasonForCause(cause error) TerminationReason {
+	switch cause {
+	case ErrMaxAttemptsReached:
+		return MaxAttempts
+	case ErrContextDeadlineExceeded:
+		return ContextDeadline
+	case ErrCircuitOpen:
+		return CircuitOpen
+	case ErrMaxElapsedTimeExceeded:
+		return MaxElapsedTime
+	case ErrBackoffBudgetExhausted:
+		return BackoffBudgetExhausted
+	case ErrStopped:
+		return Stopped
+	case ErrNonIdempotentRetry:
+		return NonIdempotent
+	case ErrRetryBudgetExhausted:
+		return RetryBudgetExhausted
+	case ErrFailureRateExceeded:
+		return FailureRateExceeded
+	case ErrStopConditionMet:
+		return StopConditionMet
+	default:
+		return ContextCanceled
+	}
+}
+
+// terminationReason 根据重试循环的返回值推断终止原因
+func terminationReason(stats Stats, err error) TerminationReason {
+	if stats.Succeeded {
+		return Succeeded
+	}
+	if retryErr, ok := err.(*RetryError); ok {
+		return retryErr.Reason
+	}
+	if err == ErrUnboundedRetry {
+		return 0
+	}
+	return NonRetryable
+}
+
+// Stats 描述一次重试执行的统计信息
+type Stats struct {
+	// Attempts 实际执行的尝试次数
+	Attempts int
+	// TotalBackoff 实际等待的重试间隔总和，不包括 InitialDelay
+	TotalBackoff time.Duration
+	// Succeeded 函数是否最终执行成功
+	Succeeded bool
+	// Reason 重试循环终止的原因
+	Reason TerminationReason
+}
+
+// AttemptRecord 描述一次尝试的执行情况
+type AttemptRecord struct {
+	// Index 尝试次数，从 1 开始
+	Index int
+	// Err 本次尝试的错误（经过 WithErrorMapper 转换后），成功时为 nil
+	Err error
+	// Backoff 本次尝试之后等待的重试间隔，没有重试时为 0
+	Backoff time.Duration
+	// Duration 本次调用函数的耗时
+	Duration time.Duration
+}
+
+// RunSummary 描述一次重试执行的完整过程，参见 WithOnComplete
+type RunSummary struct {
+	// Attempts 按顺序排列的尝试记录
+	Attempts []AttemptRecord
+	// Outcome 重试循环终止的原因
+	Outcome TerminationReason
+}
+
+// DoWithStats 执行带重试的函数，并返回本次执行的统计信息
+func DoWithStats(fn RetryableFunc, opts ...Option) (Stats, error) {
+	options := resolveOptions(opts...)
+
+	return runWithStats(options.context(), options, func(ctx context.Context, attempt int) error {
+		return fn()
+	}, false)
+}
+
+// DoWithStatsContext 执行带上下文的重试函数，并返回本次执行的统计信息
+func DoWithStatsContext(ctx context.Context, fn RetryableFuncWithContext, opts ...Option) (Stats, error) {
+	options := resolveOptions(opts...)
+
+	return runWithStats(ctx, options, func(ctx context.Context, attempt int) error {
+		return fn(ctx)
+	}, true)
+}
@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxDrainBytes 是丢弃响应前最多读取的响应体字节数，用于复用连接
+const maxDrainBytes = 4 << 10
+
+// retryTransport 是带重试的 http.RoundTripper
+type retryTransport struct {
+	base    http.RoundTripper
+	options *Options
+}
+
+// NewRetryTransport 返回带重试的 http.RoundTripper，base 为 nil 时使用 http.DefaultTransport
+// 网络错误和可重试的状态码（参见 CheckResponse）会按选项重试，并默认遵循 Retry-After。
+// 只有幂等方法（或带有 Idempotency-Key 头）且请求体可以通过 GetBody 重放的请求才会重试，
+// 其余请求只执行一次。每次重试都通过 GetBody 重新获取完整的请求体，Content-Length 等请求头保持不变；
+// 带有 Expect: 100-continue 的请求被服务端以 417 拒绝时，会在同一次尝试中去掉该头重新发送。
+// 重试次数耗尽时返回最后一次的响应，由调用方关闭响应体。
+// 由于响应体在 RoundTrip 返回后才会被读取，WithAttemptTimeout 对其不生效，请使用 http.Client.Timeout
+func NewRetryTransport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &retryTransport{
+		base:    base,
+		options: resolveOptions(append([]Option{WithRespectRetryAfter()}, opts...)...),
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper 接口
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isReplayable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	// expectContinue 表示仍然发送 Expect: 100-continue，服务端返回 417 后不再发送
+	expectContinue := true
+	err := runFunc(req.Context(), t.options, func(ctx context.Context, attempt int) error {
+		// 丢弃上一次可重试的响应
+		if resp != nil {
+			discardResponse(resp)
+			resp = nil
+		}
+
+		// 响应体在 RoundTrip 返回后才会被读取，因此不能使用单次尝试的上下文
+		r, err := attemptRequest(req.Context(), req, attempt, expectContinue)
+		if err != nil {
+			return Unrecoverable(err)
+		}
+
+		res, err := t.base.RoundTrip(r)
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusExpectationFailed && expectContinue && hasExpectContinue(req) {
+			discardResponse(res)
+			expectContinue = false
+			// 第一次发送时请求体可能已被部分读取，只有可以重放时才能重新发送
+			if r, err = attemptRequest(req.Context(), req, attempt+1, expectContinue); err != nil {
+				return Unrecoverable(err)
+			}
+			if res, err = t.base.RoundTrip(r); err != nil {
+				return err
+			}
+		}
+
+		resp = res
+		return CheckResponse(res)
+	})
+
+	if resp != nil {
+		// 重试以错误结束且上下文已经结束时，优先返回上下文错误；成功得到的响应始终返回给调用方
+		if err != nil && req.Context().Err() != nil {
+			discardResponse(resp)
+			return nil, err
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+// isReplayable 判断请求是否可以安全地重试
+func isReplayable(req *http.Request) bool {
+	if !IsIdempotentMethod(req.Method) &&
+		req.Header.Get("Idempotency-Key") == "" && req.Header.Get("X-Idempotency-Key") == "" {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// attemptRequest 返回第 attempt 次尝试使用的请求，重试时通过 GetBody 重放请求体
+// 克隆的请求保留原请求的 ContentLength；expectContinue 为 false 时去掉 Expect: 100-continue 头
+func attemptRequest(ctx context.Context, req *http.Request, attempt int, expectContinue bool) (*http.Request, error) {
+	r := req.Clone(ctx)
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	if !expectContinue {
+		r.Header.Del("Expect")
+	}
+	return r, nil
+}
+
+// hasExpectContinue 判断请求是否带有 Expect: 100-continue 头
+func hasExpectContinue(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+}
+
+// discardResponse 读取并关闭响应体，以便复用底层连接
+func discardResponse(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
@@ -0,0 +1,211 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransportFailsTwiceThenSucceeds(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, WithBackoff(ConstantBackoff(time.Millisecond)))}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("calls = %d, want 3", n)
+	}
+}
+
+// roundTripFunc 将函数适配为 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransportReturnsResponseWhenContextEndsAfterSuccess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	resp, err := NewRetryTransport(base).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("resp = %v, want 200 response", resp)
+	}
+}
+
+func TestRetryTransportRetryAfterResendsBody(t *testing.T) {
+	const payload = `{"op":"put","value":42}`
+	var (
+		mu      sync.Mutex
+		bodies  []string
+		lengths []int64
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		lengths = append(lengths, r.ContentLength)
+		n := len(bodies)
+		mu.Unlock()
+
+		if n <= 2 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: NewRetryTransport(nil,
+		WithClock(newFakeClock()),
+		WithOnBackoff(func(attempt int, delay time.Duration) { delays = append(delays, delay) }),
+	)}
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(payload))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("requests = %d, want 3", len(bodies))
+	}
+	for i := range bodies {
+		if bodies[i] != payload {
+			t.Errorf("request %d body = %q, want %q", i, bodies[i], payload)
+		}
+		if lengths[i] != int64(len(payload)) {
+			t.Errorf("request %d Content-Length = %d, want %d", i, lengths[i], len(payload))
+		}
+	}
+	if want := []time.Duration{time.Second, time.Second}; !slices.Equal(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+}
+
+func TestRetryTransportExpectContinueRejected(t *testing.T) {
+	const payload = "data"
+	var expects []string
+	var bodies []string
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		expects = append(expects, req.Header.Get("Expect"))
+		bodies = append(bodies, string(body))
+		status := http.StatusOK
+		if req.Header.Get("Expect") != "" {
+			status = http.StatusExpectationFailed
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com", strings.NewReader(payload))
+	req.Header.Set("Expect", "100-continue")
+	resp, err := NewRetryTransport(base).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if want := []string{"100-continue", ""}; !slices.Equal(expects, want) {
+		t.Errorf("Expect headers = %q, want %q", expects, want)
+	}
+	if want := []string{payload, payload}; !slices.Equal(bodies, want) {
+		t.Errorf("bodies = %q, want %q", bodies, want)
+	}
+}
+
+func TestRetryTransportDoesNotRetryUnrewindableBody(t *testing.T) {
+	calls := 0
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com", strings.NewReader("data"))
+	req.GetBody = nil
+	resp, err := NewRetryTransport(base, WithBackoff(ConstantBackoff(0))).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("status = %d after %d calls, want 503 after 1", resp.StatusCode, calls)
+	}
+}
+
+func TestDoRetriesClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := &http.Client{Timeout: 20 * time.Millisecond}
+	var calls int
+	var lastErr error
+	err := Do(func() error {
+		calls++
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			lastErr = err
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}, WithMaxAttempts(3), WithBackoff(ConstantBackoff(time.Millisecond)))
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	if !DefaultRetryable(lastErr) {
+		t.Errorf("DefaultRetryable(%v) = false, want true", lastErr)
+	}
+	if DefaultRetryable(context.DeadlineExceeded) {
+		t.Error("bare context.DeadlineExceeded should not be retryable")
+	}
+}
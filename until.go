@@ -0,0 +1,98 @@
+package retry
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrConditionNotMet 表示 DoUntil 的函数尚未报告完成
+// 重试次数耗尽时，返回的 RetryError 的 LastErr 为该错误
+var ErrConditionNotMet = errors.New("condition not met")
+
+// DoUntil 重复执行函数直到其返回 (true, nil)
+// 返回 (false, nil) 表示尚未完成，总是会继续重试，且不调用 OnRetry、不计入熔断器的失败；
+// 返回非 nil 错误时按 IsRetryable 正常判断
+func DoUntil(fn func() (bool, error), opts ...Option) error {
+	options := untilOptions(opts...)
+
+	return runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+		return untilResult(fn())
+	})
+}
+
+// DoUntilContext 重复执行带上下文的函数直到其返回 (true, nil)
+func DoUntilContext(ctx context.Context, fn func(ctx context.Context) (bool, error), opts ...Option) error {
+	options := untilOptions(opts...)
+
+	return run(ctx, options, func(ctx context.Context, attempt int) error {
+		return untilResult(fn(ctx))
+	})
+}
+
+// untilOptions 解析选项，并使 ErrConditionNotMet 始终可重试
+func untilOptions(opts ...Option) *Options {
+	options := resolveOptions(opts...)
+
+	isRetryable := options.IsRetryable
+	options.IsRetryable = func(err error) bool {
+		return err == ErrConditionNotMet || isRetryable(err)
+	}
+	return options
+}
+
+// conditionNotMet 判断错误是否表示条件尚未满足
+// 这种结果说明依赖正常响应，熔断器、有状态的重试策略和失败率窗口都不将其计为失败
+func conditionNotMet(err error) bool {
+	return err != nil && errors.Is(err, ErrConditionNotMet)
+}
+
+// untilResult 将 (done, err) 转换为重试循环使用的错误
+func untilResult(done bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !done {
+		return ErrConditionNotMet
+	}
+	return nil
+}
+
+// DoUntilStable 重复执行函数直到其连续成功 required 次（不大于 0 时按 1 处理），适用于就绪探测
+// 任何失败都会重置连续成功的计数，失败的错误按 IsRetryable 正常判断；
+// 连续成功但次数不足时总是继续执行。MaxAttempts 限制的是函数的总调用次数
+func DoUntilStable(fn RetryableFunc, required int, opts ...Option) error {
+	options := untilOptions(opts...)
+	stable := stableCounter(required)
+
+	return runFunc(options.context(), options, func(ctx context.Context, attempt int) error {
+		return stable(fn())
+	})
+}
+
+// DoUntilStableContext 重复执行带上下文的函数直到其连续成功 required 次
+func DoUntilStableContext(ctx context.Context, fn RetryableFuncWithContext, required int, opts ...Option) error {
+	options := untilOptions(opts...)
+	stable := stableCounter(required)
+
+	return run(ctx, options, func(ctx context.Context, attempt int) error {
+		return stable(fn(ctx))
+	})
+}
+
+// stableCounter 返回统计连续成功次数的函数，连续成功 required 次后返回 nil
+func stableCounter(required int) func(err error) error {
+	if required <= 0 {
+		required = 1
+	}
+
+	successes := 0
+	return func(err error) error {
+		if err != nil {
+			successes = 0
+			return err
+		}
+
+		successes++
+		return untilResult(successes >= required, nil)
+	}
+}
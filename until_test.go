@@ -0,0 +1,81 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoUntilPendingIsNeutral(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	cb := NewConsecutiveBreaker(2, time.Minute)
+
+	calls, retries := 0, 0
+	err := DoUntil(func() (bool, error) {
+		calls++
+		return calls == 4, nil
+	},
+		WithMaxAttempts(5),
+		WithBackoff(ConstantBackoff(0)),
+		WithCircuitBreaker(cb),
+		WithOnRetry(func(attempt int, err error) { retries++ }),
+		WithLogger(logger),
+	)
+
+	if err != nil {
+		t.Fatalf("DoUntil: %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+	if retries != 0 {
+		t.Errorf("OnRetry called %d times, want 0", retries)
+	}
+	if strings.Contains(logs.String(), "level=WARN") {
+		t.Errorf("unexpected warning logs:\n%s", logs.String())
+	}
+}
+
+func TestDoUntilStable(t *testing.T) {
+	// 失败、成功交替出现之后连续成功
+	results := []bool{false, true, false, true, true, false, true, true, true}
+	calls := 0
+	err := DoUntilStable(func() error {
+		ok := results[calls]
+		calls++
+		if !ok {
+			return errors.New("not ready")
+		}
+		return nil
+	}, 3, WithRetryAllErrors(), WithMaxAttempts(len(results)), WithBackoff(ConstantBackoff(0)))
+
+	if err != nil {
+		t.Fatalf("DoUntilStable: %v", err)
+	}
+	if calls != len(results) {
+		t.Errorf("calls = %d, want %d", calls, len(results))
+	}
+}
+
+func TestDoUntilStableContextNeverStable(t *testing.T) {
+	calls := 0
+	err := DoUntilStableContext(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls%2 == 0 {
+			return errors.New("not ready")
+		}
+		return nil
+	}, 2, WithRetryAllErrors(), WithMaxAttempts(6), WithBackoff(ConstantBackoff(0)))
+
+	if !errors.Is(err, ErrMaxAttemptsReached) {
+		t.Fatalf("err = %v, want ErrMaxAttemptsReached", err)
+	}
+	if calls != 6 {
+		t.Errorf("calls = %d, want 6", calls)
+	}
+}